@@ -10,7 +10,6 @@ import (
 	"path"
 	"time"
 
-	"github.com/hashicorp/go-retryablehttp"
 	"github.com/italia/publiccode-crawler/v4/common"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -20,6 +19,8 @@ type APIClient struct {
 	baseURL         string
 	retryableClient *http.Client
 	token           string
+	// breaker pauses the writes when the API keeps failing.
+	breaker *breaker
 }
 
 type Links struct {
@@ -88,18 +89,34 @@ type Software struct {
 	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
+// NewClient returns a client for the API at API_BASEURL, with the Options
+// in the configuration.
 func NewClient() APIClient {
-	retryableClient := retryablehttp.NewClient().StandardClient()
+	return newClient(viper.GetString("API_BASEURL"), viper.GetString("API_BEARER_TOKEN"), OptionsFromConfig())
+}
+
+// NewUpstreamClient returns a read-only client for another developers-italia-api
+// compatible instance, eg. a regional catalog federating into this one.
+func NewUpstreamClient(baseURL string) APIClient {
+	return newClient(baseURL, "", OptionsFromConfig())
+}
 
-	return APIClient{
-		baseURL:         viper.GetString("API_BASEURL"),
-		retryableClient: retryableClient,
-		token:           "Bearer " + viper.GetString("API_BEARER_TOKEN"),
+func newClient(baseURL string, token string, opts Options) APIClient {
+	clt := APIClient{
+		baseURL:         baseURL,
+		retryableClient: newHTTPClient(opts),
+		breaker:         newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
 	}
+
+	if token != "" {
+		clt.token = "Bearer " + token
+	}
+
+	return clt
 }
 
-func (clt APIClient) Get(url string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+func (clt APIClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -107,9 +124,9 @@ func (clt APIClient) Get(url string) (*http.Response, error) {
 	return clt.retryableClient.Do(req)
 }
 
-func (clt APIClient) Post(url string, body []byte) (*http.Response, error) {
+func (clt APIClient) Post(ctx context.Context, url string, body []byte) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodPost,
 		url,
 		bytes.NewBuffer(body),
@@ -121,12 +138,12 @@ func (clt APIClient) Post(url string, body []byte) (*http.Response, error) {
 	req.Header.Add("Authorization", clt.token)
 	req.Header.Add("Content-Type", "application/json")
 
-	return clt.retryableClient.Do(req)
+	return clt.write(req)
 }
 
-func (clt APIClient) Patch(url string, body []byte) (*http.Response, error) {
+func (clt APIClient) Patch(ctx context.Context, url string, body []byte) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(
-		context.Background(),
+		ctx,
 		http.MethodPatch,
 		url,
 		bytes.NewBuffer(body),
@@ -138,39 +155,35 @@ func (clt APIClient) Patch(url string, body []byte) (*http.Response, error) {
 	req.Header.Add("Authorization", clt.token)
 	req.Header.Add("Content-Type", "application/merge-patch+json")
 
-	return clt.retryableClient.Do(req)
+	return clt.write(req)
 }
 
-// GetPublishers returns a slice with all the publishers from the API and
-// any error encountered.
-func (clt APIClient) GetPublishers() ([]common.Publisher, error) {
-	var publishersResponse *PublishersPaginated
-
-	pageAfter := ""
-	publishers := make([]common.Publisher, 0, 25)
-
-page:
-	reqURL := joinPath(clt.baseURL, "/publishers") + pageAfter
-
-	res, err := clt.Get(reqURL)
+// write sends a request changing data in the API, waiting first if the
+// circuit breaker paused the writes. A write given up while waiting wasn't
+// sent, so it fails with a *url.Error as http.Client does.
+func (clt APIClient) write(req *http.Request) (*http.Response, error) {
+	probe, err := clt.breaker.wait(req.Context())
 	if err != nil {
-		return nil, fmt.Errorf("can't get publishers %s: %w", reqURL, err)
+		return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: err}
 	}
 
-	defer res.Body.Close()
-
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, fmt.Errorf("can't get publishers %s: HTTP status %s", reqURL, res.Status)
-	}
+	res, err := clt.retryableClient.Do(req)
+	clt.breaker.record(probe, res, err)
 
-	publishersResponse = &PublishersPaginated{}
+	return res, err
+}
 
-	err = json.NewDecoder(res.Body).Decode(&publishersResponse)
+// GetPublishers returns a slice with all the publishers from the API and
+// any error encountered.
+func (clt APIClient) GetPublishers(ctx context.Context) ([]common.Publisher, error) {
+	apiPublishers, err := clt.GetAPIPublishers(ctx, false)
 	if err != nil {
-		return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+		return nil, err
 	}
 
-	for _, pub := range publishersResponse.Data {
+	publishers := make([]common.Publisher, 0, len(apiPublishers))
+
+	for _, pub := range apiPublishers {
 		publisher := common.Publisher{
 			ID:            pub.ID,
 			AlternativeID: pub.AlternativeID,
@@ -180,7 +193,7 @@ page:
 		for _, hosting := range pub.CodeHostings {
 			hostingURL, err := url.Parse(hosting.URL)
 			if err != nil {
-				return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+				return nil, fmt.Errorf("can't parse code hosting %s of publisher %s: %w", hosting.URL, pub.ID, err)
 			}
 
 			var driver string
@@ -193,17 +206,62 @@ page:
 				driver = common.InferVCSDriver(*hostingURL)
 			}
 
+			filter, args, err := common.ParseRepoFilterArgs(args)
+			if err != nil {
+				log.Errorf("publisher %s: skipping %s: %s", pub.ID, hosting.URL, err.Error())
+
+				continue
+			}
+
 			publisher.Sources = append(publisher.Sources, common.CodeHosting{
 				URL:    *hostingURL,
 				Driver: driver,
 				Args:   args,
 				Group:  hosting.Group,
+				Filter: filter,
 			})
 		}
 
 		publishers = append(publishers, publisher)
 	}
 
+	return publishers, nil
+}
+
+// GetAPIPublishers returns the publishers as the API represents them. With
+// all, the inactive publishers are returned too.
+func (clt APIClient) GetAPIPublishers(ctx context.Context, all bool) ([]Publisher, error) {
+	var publishersResponse *PublishersPaginated
+
+	pageAfter := ""
+	publishers := make([]Publisher, 0, 25)
+
+page:
+	reqURL := joinPath(clt.baseURL, "/publishers") + pageAfter
+	if all {
+		reqURL = withQuery(reqURL, "all", "true")
+	}
+
+	res, err := clt.Get(ctx, reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't get publishers %s: %w", reqURL, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("can't get publishers %s: HTTP status %s", reqURL, res.Status)
+	}
+
+	publishersResponse = &PublishersPaginated{}
+
+	err = json.NewDecoder(res.Body).Decode(&publishersResponse)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+	}
+
+	publishers = append(publishers, publishersResponse.Data...)
+
 	if publishersResponse.Links.Next != "" {
 		pageAfter = publishersResponse.Links.Next
 
@@ -213,35 +271,122 @@ page:
 	return publishers, nil
 }
 
-// GetCatalogs returns all catalogs from the API with their sources.
-func (clt APIClient) GetCatalogs() ([]common.Catalog, error) {
-	var catalogsResponse *CatalogsPaginated
+// PostPublisher creates a new publisher with the alternative ID, description,
+// email, code hostings and active flag of publisher, and returns it as
+// created by the API.
+func (clt APIClient) PostPublisher(ctx context.Context, publisher Publisher) (*Publisher, error) {
+	fields := map[string]any{
+		"alternativeId": publisher.AlternativeID,
+		"description":   publisher.Description,
+		"codeHosting":   codeHostingsBody(publisher.CodeHostings),
+		"active":        publisher.Active,
+	}
+	if publisher.Email != "" {
+		fields["email"] = publisher.Email
+	}
 
-	pageAfter := ""
-	catalogs := make([]common.Catalog, 0, 10)
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("can't create publisher: %w", err)
+	}
 
-page:
-	reqURL := joinPath(clt.baseURL, "/catalogs") + "?all=true" + pageAfter
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, "/publishers"), body)
+	if err != nil {
+		return nil, fmt.Errorf("can't create publisher: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("can't create publisher: %w", newHTTPError(res))
+	}
+
+	response := &Publisher{}
 
-	res, err := clt.Get(reqURL)
+	err = json.NewDecoder(res.Body).Decode(&response)
 	if err != nil {
-		return nil, fmt.Errorf("can't get catalogs %s: %w", reqURL, err)
+		return nil, fmt.Errorf("can't parse POST /publishers response: %w", err)
+	}
+
+	return response, nil
+}
+
+// PublisherPatch holds the fields to change in a publisher. Nil fields are
+// left as they are.
+type PublisherPatch struct {
+	AlternativeID *string
+	Description   *string
+	CodeHostings  []CodeHosting
+	Active        *bool
+}
+
+// PatchPublisher updates the fields set in patch of the publisher with the
+// given id.
+func (clt APIClient) PatchPublisher(ctx context.Context, publisherID string, patch PublisherPatch) error {
+	fields := map[string]any{}
+
+	if patch.AlternativeID != nil {
+		fields["alternativeId"] = *patch.AlternativeID
+	}
+
+	if patch.Description != nil {
+		fields["description"] = *patch.Description
+	}
+
+	if patch.CodeHostings != nil {
+		fields["codeHosting"] = codeHostingsBody(patch.CodeHostings)
+	}
+
+	if patch.Active != nil {
+		fields["active"] = *patch.Active
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("can't update publisher %s: %w", publisherID, err)
+	}
+
+	res, err := clt.Patch(ctx, joinPath(clt.baseURL, "/publishers", publisherID), body)
+	if err != nil {
+		return fmt.Errorf("can't update publisher %s: %w", publisherID, err)
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, fmt.Errorf("can't get catalogs %s: HTTP status %s", reqURL, res.Status)
+		return fmt.Errorf("can't update publisher %s: %w", publisherID, newHTTPError(res))
 	}
 
-	catalogsResponse = &CatalogsPaginated{}
+	return nil
+}
 
-	err = json.NewDecoder(res.Body).Decode(&catalogsResponse)
+// codeHostingsBody returns hostings as sent to the API, without the
+// timestamps it manages.
+func codeHostingsBody(hostings []CodeHosting) []map[string]any {
+	body := make([]map[string]any, 0, len(hostings))
+
+	for _, hosting := range hostings {
+		item := map[string]any{"url": hosting.URL, "group": hosting.Group}
+		if len(hosting.Type) > 0 {
+			item["type"] = hosting.Type
+		}
+
+		body = append(body, item)
+	}
+
+	return body
+}
+
+// GetCatalogs returns all catalogs from the API with their sources.
+func (clt APIClient) GetCatalogs(ctx context.Context) ([]common.Catalog, error) {
+	apiCatalogs, err := clt.GetAPICatalogs(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+		return nil, err
 	}
 
-	for _, catEntry := range catalogsResponse.Data {
+	catalogs := make([]common.Catalog, 0, len(apiCatalogs))
+
+	for _, catEntry := range apiCatalogs {
 		catalogID := catEntry.ID
 		if catEntry.AlternativeID != "" {
 			catalogID = catEntry.AlternativeID
@@ -256,7 +401,7 @@ page:
 		for _, source := range catEntry.Sources {
 			sourceURL, err := url.Parse(source.URL)
 			if err != nil {
-				return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+				return nil, fmt.Errorf("can't parse source %s of catalog %s: %w", source.URL, catalogID, err)
 			}
 
 			var driver string
@@ -266,16 +411,58 @@ page:
 				driver = common.InferVCSDriver(*sourceURL)
 			}
 
+			filter, args, err := common.ParseRepoFilterArgs(source.Args)
+			if err != nil {
+				log.Errorf("catalog %s: skipping %s: %s", catalogID, source.URL, err.Error())
+
+				continue
+			}
+
 			cat.Sources = append(cat.Sources, common.CatalogSource{
 				URL:    *sourceURL,
 				Driver: driver,
-				Args:   source.Args,
+				Args:   args,
+				Filter: filter,
 			})
 		}
 
 		catalogs = append(catalogs, cat)
 	}
 
+	return catalogs, nil
+}
+
+// GetAPICatalogs returns all the catalogs, active and inactive, as the API
+// represents them.
+func (clt APIClient) GetAPICatalogs(ctx context.Context) ([]APICatalog, error) {
+	var catalogsResponse *CatalogsPaginated
+
+	pageAfter := ""
+	catalogs := make([]APICatalog, 0, 10)
+
+page:
+	reqURL := withQuery(joinPath(clt.baseURL, "/catalogs")+pageAfter, "all", "true")
+
+	res, err := clt.Get(ctx, reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't get catalogs %s: %w", reqURL, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("can't get catalogs %s: HTTP status %s", reqURL, res.Status)
+	}
+
+	catalogsResponse = &CatalogsPaginated{}
+
+	err = json.NewDecoder(res.Body).Decode(&catalogsResponse)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+	}
+
+	catalogs = append(catalogs, catalogsResponse.Data...)
+
 	if catalogsResponse.Links.Next != "" {
 		pageAfter = catalogsResponse.Links.Next
 
@@ -285,6 +472,41 @@ page:
 	return catalogs, nil
 }
 
+// PostCatalog creates a new catalog with the fields of catalog, and returns
+// it as created by the API.
+func (clt APIClient) PostCatalog(ctx context.Context, catalog APICatalog) (*APICatalog, error) {
+	body, err := json.Marshal(map[string]any{
+		"alternativeId":       catalog.AlternativeID,
+		"name":                catalog.Name,
+		"publishersNamespace": catalog.PublishersNamespace,
+		"active":              catalog.Active,
+		"sources":             catalog.Sources,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't create catalog: %w", err)
+	}
+
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, "/catalogs"), body)
+	if err != nil {
+		return nil, fmt.Errorf("can't create catalog: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("can't create catalog: %w", newHTTPError(res))
+	}
+
+	response := &APICatalog{}
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse POST /catalogs response: %w", err)
+	}
+
+	return response, nil
+}
+
 func catalogPath(catalogID string, segments ...string) string {
 	parts := append([]string{"/catalogs/", catalogID}, segments...)
 
@@ -293,12 +515,14 @@ func catalogPath(catalogID string, segments ...string) string {
 
 // GetCatalogSoftwareByURL returns the software matching the given repo URL
 // within the given catalog. Returns (nil, nil) if not found.
-func (clt APIClient) GetCatalogSoftwareByURL(catalogID string, softwareURL string) (*Software, error) {
+func (clt APIClient) GetCatalogSoftwareByURL(
+	ctx context.Context, catalogID string, softwareURL string,
+) (*Software, error) {
 	var softwareResponse SoftwarePaginated
 
 	reqURL := joinPath(clt.baseURL, catalogPath(catalogID, "software")) + "?url=" + softwareURL
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("can't GET catalog %s software by url: %w", catalogID, err)
 	}
@@ -324,7 +548,7 @@ func (clt APIClient) GetCatalogSoftwareByURL(catalogID string, softwareURL strin
 
 // PostCatalogSoftware creates a new software resource within the given catalog.
 func (clt APIClient) PostCatalogSoftware(
-	catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
+	ctx context.Context, catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
 ) (*Software, error) {
 	body, err := json.Marshal(map[string]any{
 		"publiccodeYml": publiccodeYml,
@@ -336,7 +560,7 @@ func (clt APIClient) PostCatalogSoftware(
 		return nil, fmt.Errorf("can't create software in catalog %s: %w", catalogID, err)
 	}
 
-	res, err := clt.Post(joinPath(clt.baseURL, catalogPath(catalogID, "software")), body)
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, catalogPath(catalogID, "software")), body)
 	if err != nil {
 		return nil, fmt.Errorf("can't create software in catalog %s: %w", catalogID, err)
 	}
@@ -344,7 +568,7 @@ func (clt APIClient) PostCatalogSoftware(
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, fmt.Errorf("can't create software in catalog %s: API replied with HTTP %s", catalogID, res.Status)
+		return nil, fmt.Errorf("can't create software in catalog %s: %w", catalogID, newHTTPError(res))
 	}
 
 	response := &Software{}
@@ -359,7 +583,7 @@ func (clt APIClient) PostCatalogSoftware(
 
 // PatchCatalogSoftware updates a software resource within the given catalog.
 func (clt APIClient) PatchCatalogSoftware(
-	catalogID string, softwareID string, softwareURL string, aliases []string, publiccodeYml string,
+	ctx context.Context, catalogID string, softwareID string, softwareURL string, aliases []string, publiccodeYml string,
 ) error {
 	body, err := json.Marshal(map[string]any{
 		"publiccodeYml": publiccodeYml,
@@ -371,7 +595,7 @@ func (clt APIClient) PatchCatalogSoftware(
 	}
 
 	res, err := clt.Patch(
-		joinPath(clt.baseURL, catalogPath(catalogID, "software", softwareID)), body,
+		ctx, joinPath(clt.baseURL, catalogPath(catalogID, "software", softwareID)), body,
 	)
 	if err != nil {
 		return fmt.Errorf("can't update software in catalog %s: %w", catalogID, err)
@@ -380,14 +604,16 @@ func (clt APIClient) PatchCatalogSoftware(
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't update software in catalog %s: API replied with HTTP %s", catalogID, res.Status)
+		return fmt.Errorf("can't update software in catalog %s: %w", catalogID, newHTTPError(res))
 	}
 
 	return nil
 }
 
 // PostCatalogSoftwareLog creates a log entry for the given software within a catalog.
-func (clt APIClient) PostCatalogSoftwareLog(catalogID string, softwareID string, message string) error {
+func (clt APIClient) PostCatalogSoftwareLog(
+	ctx context.Context, catalogID string, softwareID string, message string,
+) error {
 	payload, err := json.Marshal(map[string]any{
 		"message": message,
 	})
@@ -396,7 +622,7 @@ func (clt APIClient) PostCatalogSoftwareLog(catalogID string, softwareID string,
 	}
 
 	res, err := clt.Post(
-		joinPath(clt.baseURL, catalogPath(catalogID, "software", softwareID, "logs")), payload,
+		ctx, joinPath(clt.baseURL, catalogPath(catalogID, "software", softwareID, "logs")), payload,
 	)
 	if err != nil {
 		return fmt.Errorf("can't create software log: %w", err)
@@ -405,14 +631,14 @@ func (clt APIClient) PostCatalogSoftwareLog(catalogID string, softwareID string,
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't create software log: API replied with HTTP %s", res.Status)
+		return fmt.Errorf("can't create software log: %w", newHTTPError(res))
 	}
 
 	return nil
 }
 
 // PostCatalogLog creates a general log entry for the given catalog.
-func (clt APIClient) PostCatalogLog(catalogID string, message string) error {
+func (clt APIClient) PostCatalogLog(ctx context.Context, catalogID string, message string) error {
 	payload, err := json.Marshal(map[string]any{
 		"message": message,
 	})
@@ -420,7 +646,7 @@ func (clt APIClient) PostCatalogLog(catalogID string, message string) error {
 		return fmt.Errorf("can't create catalog log: %w", err)
 	}
 
-	res, err := clt.Post(joinPath(clt.baseURL, catalogPath(catalogID, "logs")), payload)
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, catalogPath(catalogID, "logs")), payload)
 	if err != nil {
 		return fmt.Errorf("can't create catalog log: %w", err)
 	}
@@ -428,19 +654,80 @@ func (clt APIClient) PostCatalogLog(catalogID string, message string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't create catalog log: API replied with HTTP %s", res.Status)
+		return fmt.Errorf("can't create catalog log: %w", newHTTPError(res))
+	}
+
+	return nil
+}
+
+// ForEachSoftware pages through all the active software in the API, calling
+// fn for each one. It stops at the first error returned by fn.
+func (clt APIClient) ForEachSoftware(ctx context.Context, fn func(Software) error) error {
+	return clt.ForEachCatalogSoftware(ctx, "", false, fn)
+}
+
+// ForEachCatalogSoftware pages through the software of the given catalog,
+// or of the whole API if catalogID is empty, calling fn for each one. With
+// all, the inactive software is included. It stops at the first error
+// returned by fn.
+func (clt APIClient) ForEachCatalogSoftware(
+	ctx context.Context, catalogID string, all bool, fn func(Software) error,
+) error {
+	var softwareResponse *SoftwarePaginated
+
+	softwarePath := "/software"
+	if catalogID != "" {
+		softwarePath = catalogPath(catalogID, "software")
+	}
+
+	pageAfter := ""
+
+page:
+	reqURL := joinPath(clt.baseURL, softwarePath) + pageAfter
+	if all {
+		reqURL = withQuery(reqURL, "all", "true")
+	}
+
+	res, err := clt.Get(ctx, reqURL)
+	if err != nil {
+		return fmt.Errorf("can't get software %s: %w", reqURL, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("can't get software %s: HTTP status %s", reqURL, res.Status)
+	}
+
+	softwareResponse = &SoftwarePaginated{}
+
+	err = json.NewDecoder(res.Body).Decode(&softwareResponse)
+	if err != nil {
+		return fmt.Errorf("can't parse GET %s response: %w", reqURL, err)
+	}
+
+	for _, software := range softwareResponse.Data {
+		if err := fn(software); err != nil {
+			return err
+		}
+	}
+
+	if softwareResponse.Links.Next != "" {
+		pageAfter = softwareResponse.Links.Next
+
+		goto page
 	}
 
 	return nil
 }
 
 // GetSoftware returns the software with the given id or any error encountered.
-func (clt APIClient) GetSoftware(softwareID string) (*Software, error) {
+func (clt APIClient) GetSoftware(ctx context.Context, softwareID string) (*Software, error) {
 	var softwareResponse Software
 
 	url := joinPath(clt.baseURL, "/software") + "/" + softwareID
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("can't GET /software/%s: %w", softwareID, err)
 	}
@@ -463,12 +750,12 @@ func (clt APIClient) GetSoftware(softwareID string) (*Software, error) {
 // GetSoftwareByURL returns the software matching the given repo URL and
 // any error encountered.
 // In case no software is found and no error occours, (nil, nil) is returned.
-func (clt APIClient) GetSoftwareByURL(url string) (*Software, error) {
+func (clt APIClient) GetSoftwareByURL(ctx context.Context, url string) (*Software, error) {
 	var softwareResponse SoftwarePaginated
 
 	reqURL := joinPath(clt.baseURL, "/software") + "?url=" + url
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("can't GET /software?url=%s: %w", url, err)
 	}
@@ -494,7 +781,9 @@ func (clt APIClient) GetSoftwareByURL(url string) (*Software, error) {
 
 // PostSoftware creates a new software resource with the given fields and returns
 // a Software struct or any error encountered.
-func (clt APIClient) PostSoftware(url string, aliases []string, publiccodeYml string, active bool) (*Software, error) {
+func (clt APIClient) PostSoftware(
+	ctx context.Context, url string, aliases []string, publiccodeYml string, active bool,
+) (*Software, error) {
 	body, err := json.Marshal(map[string]any{
 		"publiccodeYml": publiccodeYml,
 		"url":           url,
@@ -505,7 +794,7 @@ func (clt APIClient) PostSoftware(url string, aliases []string, publiccodeYml st
 		return nil, fmt.Errorf("can't create software: %w", err)
 	}
 
-	res, err := clt.Post(joinPath(clt.baseURL, "/software"), body)
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, "/software"), body)
 	if err != nil {
 		return nil, fmt.Errorf("can't create software: %w", err)
 	}
@@ -513,7 +802,7 @@ func (clt APIClient) PostSoftware(url string, aliases []string, publiccodeYml st
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil, fmt.Errorf("can't create software: API replied with HTTP %s", res.Status)
+		return nil, fmt.Errorf("can't create software: %w", newHTTPError(res))
 	}
 
 	postSoftwareResponse := &Software{}
@@ -529,7 +818,7 @@ func (clt APIClient) PostSoftware(url string, aliases []string, publiccodeYml st
 // PatchSoftware updates a software resource with the given fields and returns
 // any error encountered.
 func (clt APIClient) PatchSoftware(
-	softwareID string, url string, aliases []string, publiccodeYml string,
+	ctx context.Context, softwareID string, url string, aliases []string, publiccodeYml string,
 ) error {
 	body, err := json.Marshal(map[string]any{
 		"publiccodeYml": publiccodeYml,
@@ -540,7 +829,7 @@ func (clt APIClient) PatchSoftware(
 		return fmt.Errorf("can't update software: %w", err)
 	}
 
-	res, err := clt.Patch(joinPath(clt.baseURL, "/software/"+softwareID), body)
+	res, err := clt.Patch(ctx, joinPath(clt.baseURL, "/software/"+softwareID), body)
 	if err != nil {
 		return fmt.Errorf("can't update software: %w", err)
 	}
@@ -548,7 +837,7 @@ func (clt APIClient) PatchSoftware(
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't update software: API replied with HTTP %s", res.Status)
+		return fmt.Errorf("can't update software: %w", newHTTPError(res))
 	}
 
 	return nil
@@ -556,7 +845,7 @@ func (clt APIClient) PatchSoftware(
 
 // PostSoftwareLog creates a new software log with the given fields and returns
 // any error encountered.
-func (clt APIClient) PostSoftwareLog(softwareID string, message string) error {
+func (clt APIClient) PostSoftwareLog(ctx context.Context, softwareID string, message string) error {
 	payload, err := json.Marshal(map[string]any{
 		"message": message,
 	})
@@ -564,7 +853,7 @@ func (clt APIClient) PostSoftwareLog(softwareID string, message string) error {
 		return fmt.Errorf("can't create log: %w", err)
 	}
 
-	res, err := clt.Post(joinPath(clt.baseURL, "/software/", softwareID, "logs"), payload)
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, "/software/", softwareID, "logs"), payload)
 	if err != nil {
 		return fmt.Errorf("can't create software log: %w", err)
 	}
@@ -572,14 +861,14 @@ func (clt APIClient) PostSoftwareLog(softwareID string, message string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't create software log: API replied with HTTP %s", res.Status)
+		return fmt.Errorf("can't create software log: %w", newHTTPError(res))
 	}
 
 	return nil
 }
 
 // PostLog creates a new log with the given message and returns any error encountered.
-func (clt APIClient) PostLog(message string) error {
+func (clt APIClient) PostLog(ctx context.Context, message string) error {
 	payload, err := json.Marshal(map[string]any{
 		"message": message,
 	})
@@ -587,7 +876,7 @@ func (clt APIClient) PostLog(message string) error {
 		return fmt.Errorf("can't create log: %w", err)
 	}
 
-	res, err := clt.Post(joinPath(clt.baseURL, "/logs"), payload)
+	res, err := clt.Post(ctx, joinPath(clt.baseURL, "/logs"), payload)
 	if err != nil {
 		return fmt.Errorf("can't create log: %w", err)
 	}
@@ -595,12 +884,26 @@ func (clt APIClient) PostLog(message string) error {
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return fmt.Errorf("can't create log: API replied with HTTP %s", res.Status)
+		return fmt.Errorf("can't create log: %w", newHTTPError(res))
 	}
 
 	return nil
 }
 
+// withQuery returns rawURL with the query parameter key set to value.
+func withQuery(rawURL, key, value string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsedURL.Query()
+	query.Set(key, value)
+	parsedURL.RawQuery = query.Encode()
+
+	return parsedURL.String()
+}
+
 func joinPath(base string, paths ...string) string {
 	parsedURL, err := url.Parse(base)
 	if err != nil {
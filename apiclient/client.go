@@ -0,0 +1,57 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/spf13/viper"
+)
+
+// Client is the API the crawler loads publishers and catalogs from and saves
+// the crawled software and logs to. APIClient implements it with
+// developers-italia-api, MemoryClient in memory.
+type Client interface {
+	GetPublishers(ctx context.Context) ([]common.Publisher, error)
+	GetCatalogs(ctx context.Context) ([]common.Catalog, error)
+
+	GetSoftware(ctx context.Context, softwareID string) (*Software, error)
+	GetSoftwareByURL(ctx context.Context, url string) (*Software, error)
+	ForEachCatalogSoftware(ctx context.Context, catalogID string, all bool, fn func(Software) error) error
+	PostSoftware(
+		ctx context.Context, url string, aliases []string, publiccodeYml string, active bool,
+	) (*Software, error)
+	PatchSoftware(ctx context.Context, softwareID string, url string, aliases []string, publiccodeYml string) error
+	PostSoftwareLog(ctx context.Context, softwareID string, message string) error
+	PostLog(ctx context.Context, message string) error
+
+	GetCatalogSoftwareByURL(ctx context.Context, catalogID string, softwareURL string) (*Software, error)
+	PostCatalogSoftware(
+		ctx context.Context, catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
+	) (*Software, error)
+	PatchCatalogSoftware(
+		ctx context.Context,
+		catalogID string, softwareID string, softwareURL string, aliases []string, publiccodeYml string,
+	) error
+	PostCatalogSoftwareLog(ctx context.Context, catalogID string, softwareID string, message string) error
+	PostCatalogLog(ctx context.Context, catalogID string, message string) error
+}
+
+var (
+	_ Client = APIClient{}
+	_ Client = (*MemoryClient)(nil)
+)
+
+// New returns the Client selected by the API_BACKEND setting: "http" (the
+// default) for developers-italia-api at API_BASEURL, "memory" for a
+// MemoryClient, which starts empty and is lost at exit.
+func New() (Client, error) {
+	switch backend := viper.GetString("API_BACKEND"); backend {
+	case "", "http":
+		return NewClient(), nil
+	case "memory":
+		return NewMemoryClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown API_BACKEND %q, expected http or memory", backend)
+	}
+}
@@ -0,0 +1,44 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// HTTPError is the error of a request the API replied to with a non 2xx
+// status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func newHTTPError(res *http.Response) *HTTPError {
+	return &HTTPError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+func (e *HTTPError) Error() string {
+	return "API replied with HTTP " + e.Status
+}
+
+// IsUnavailable reports whether err is the API being unreachable or failing,
+// rather than refusing the request: the same request can succeed later.
+//
+// Only a *url.Error, what http.Client returns when it got no response, or
+// a 429 or 5xx HTTPError count: other errors, as a reply to a write that
+// can't be decoded, may come after the API applied the request.
+func IsUnavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var urlErr *url.Error
+
+	return errors.As(err, &urlErr)
+}
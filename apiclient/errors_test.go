@@ -0,0 +1,56 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	down := &url.Error{Op: "Post", URL: "https://api.example.org/software", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{fmt.Errorf("can't create software: %w", down), true},
+		{&HTTPError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}, true},
+		{&HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, true},
+		{&HTTPError{StatusCode: http.StatusUnprocessableEntity, Status: "422 Unprocessable Entity"}, false},
+		{errors.New("invalid character 'o' in literal null"), false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, IsUnavailable(tc.err), tc.err)
+	}
+}
+
+// A write the API applied, whose reply can't be decoded, mustn't be retried.
+func TestIsUnavailable_undecodableReply(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer srv.Close()
+
+	clt := newClient(srv.URL, "token", testOptions())
+
+	_, err := clt.PostSoftware(t.Context(), "https://example.org/app", nil, "yml", true)
+	require.Error(t, err)
+	assert.False(t, IsUnavailable(err))
+
+	srv.Close()
+
+	_, err = clt.PostSoftware(t.Context(), "https://example.org/app", nil, "yml", true)
+	require.Error(t, err)
+	assert.True(t, IsUnavailable(err))
+}
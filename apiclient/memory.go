@@ -0,0 +1,300 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+)
+
+// MemoryClient is a Client keeping everything in memory, for crawls and
+// tests without developers-italia-api. It follows the API's semantics:
+// software is found by URL or alias, each catalog has its own software,
+// and the API sets the IDs and the timestamps.
+//
+// It's safe for concurrent use.
+type MemoryClient struct {
+	mu sync.Mutex
+
+	publishers []common.Publisher
+	catalogs   []common.Catalog
+	// software by catalog ID, "" for the software outside of catalogs.
+	software map[string][]*Software
+	logs     []Log
+
+	// now returns the time for the timestamps.
+	now func() time.Time
+}
+
+// Log is a log entry saved by a MemoryClient. SoftwareID and CatalogID
+// are empty for general logs.
+type Log struct {
+	SoftwareID string
+	CatalogID  string
+	Message    string
+	CreatedAt  time.Time
+}
+
+// The errors of a MemoryClient, as the API replies.
+var (
+	errNotFound = &HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	errConflict = &HTTPError{StatusCode: http.StatusConflict, Status: "409 Conflict"}
+)
+
+// NewMemoryClient returns an empty MemoryClient.
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		software: map[string][]*Software{},
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// AddPublishers adds publishers to the ones returned by GetPublishers.
+func (clt *MemoryClient) AddPublishers(publishers ...common.Publisher) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	clt.publishers = append(clt.publishers, publishers...)
+}
+
+// AddCatalogs adds catalogs to the ones returned by GetCatalogs.
+func (clt *MemoryClient) AddCatalogs(catalogs ...common.Catalog) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	clt.catalogs = append(clt.catalogs, catalogs...)
+}
+
+// Software returns a copy of the software of the given catalog, or outside
+// of catalogs if catalogID is empty, in creation order.
+func (clt *MemoryClient) Software(catalogID string) []Software {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	software := make([]Software, 0, len(clt.software[catalogID]))
+	for _, s := range clt.software[catalogID] {
+		software = append(software, copySoftware(s))
+	}
+
+	return software
+}
+
+// Logs returns a copy of the saved logs, in creation order.
+func (clt *MemoryClient) Logs() []Log {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	return slices.Clone(clt.logs)
+}
+
+func (clt *MemoryClient) GetPublishers(_ context.Context) ([]common.Publisher, error) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	return slices.Clone(clt.publishers), nil
+}
+
+func (clt *MemoryClient) GetCatalogs(_ context.Context) ([]common.Catalog, error) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	return slices.Clone(clt.catalogs), nil
+}
+
+func (clt *MemoryClient) GetSoftware(_ context.Context, softwareID string) (*Software, error) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	for _, software := range clt.software[""] {
+		if software.ID == softwareID {
+			s := copySoftware(software)
+
+			return &s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't GET /software/%s: %w", softwareID, errNotFound)
+}
+
+func (clt *MemoryClient) GetSoftwareByURL(ctx context.Context, url string) (*Software, error) {
+	return clt.GetCatalogSoftwareByURL(ctx, "", url)
+}
+
+// ForEachCatalogSoftware calls fn for each software of the given catalog, or
+// outside of catalogs if catalogID is empty, in creation order. With all, the
+// inactive software is included. It stops at the first error returned by fn.
+func (clt *MemoryClient) ForEachCatalogSoftware(
+	ctx context.Context, catalogID string, all bool, fn func(Software) error,
+) error {
+	for _, software := range clt.Software(catalogID) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !all && !software.Active {
+			continue
+		}
+
+		if err := fn(software); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (clt *MemoryClient) PostSoftware(
+	ctx context.Context, url string, aliases []string, publiccodeYml string, active bool,
+) (*Software, error) {
+	return clt.PostCatalogSoftware(ctx, "", url, aliases, publiccodeYml, active)
+}
+
+func (clt *MemoryClient) PatchSoftware(
+	ctx context.Context, softwareID string, url string, aliases []string, publiccodeYml string,
+) error {
+	return clt.PatchCatalogSoftware(ctx, "", softwareID, url, aliases, publiccodeYml)
+}
+
+func (clt *MemoryClient) PostSoftwareLog(ctx context.Context, softwareID string, message string) error {
+	return clt.PostCatalogSoftwareLog(ctx, "", softwareID, message)
+}
+
+func (clt *MemoryClient) PostLog(_ context.Context, message string) error {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	clt.logs = append(clt.logs, Log{Message: message, CreatedAt: clt.now()})
+
+	return nil
+}
+
+func (clt *MemoryClient) GetCatalogSoftwareByURL(
+	_ context.Context, catalogID string, softwareURL string,
+) (*Software, error) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	for _, software := range clt.software[catalogID] {
+		if software.URL == softwareURL || slices.Contains(software.Aliases, softwareURL) {
+			s := copySoftware(software)
+
+			return &s, nil
+		}
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+func (clt *MemoryClient) PostCatalogSoftware(
+	_ context.Context, catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
+) (*Software, error) {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	for _, software := range clt.software[catalogID] {
+		if software.URL == softwareURL {
+			return nil, fmt.Errorf("can't create software %s: %w", softwareURL, errConflict)
+		}
+	}
+
+	now := clt.now()
+	software := &Software{
+		ID:            newMemoryID(),
+		URL:           softwareURL,
+		Aliases:       slices.Clone(aliases),
+		PubliccodeYml: publiccodeYml,
+		Active:        active,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	clt.software[catalogID] = append(clt.software[catalogID], software)
+
+	s := copySoftware(software)
+
+	return &s, nil
+}
+
+func (clt *MemoryClient) PatchCatalogSoftware(
+	_ context.Context, catalogID string, softwareID string, softwareURL string, aliases []string, publiccodeYml string,
+) error {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	software := clt.find(catalogID, softwareID)
+	if software == nil {
+		return fmt.Errorf("can't update software %s: %w", softwareID, errNotFound)
+	}
+
+	software.URL = softwareURL
+	software.Aliases = slices.Clone(aliases)
+	software.PubliccodeYml = publiccodeYml
+	software.UpdatedAt = clt.now()
+
+	return nil
+}
+
+func (clt *MemoryClient) PostCatalogSoftwareLog(
+	_ context.Context, catalogID string, softwareID string, message string,
+) error {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	if clt.find(catalogID, softwareID) == nil {
+		return fmt.Errorf("can't create log of software %s: %w", softwareID, errNotFound)
+	}
+
+	clt.logs = append(clt.logs, Log{
+		SoftwareID: softwareID,
+		CatalogID:  catalogID,
+		Message:    message,
+		CreatedAt:  clt.now(),
+	})
+
+	return nil
+}
+
+func (clt *MemoryClient) PostCatalogLog(_ context.Context, catalogID string, message string) error {
+	clt.mu.Lock()
+	defer clt.mu.Unlock()
+
+	clt.logs = append(clt.logs, Log{CatalogID: catalogID, Message: message, CreatedAt: clt.now()})
+
+	return nil
+}
+
+// find returns the software with the given ID in catalogID, or nil. The
+// caller must hold mu.
+func (clt *MemoryClient) find(catalogID string, softwareID string) *Software {
+	for _, software := range clt.software[catalogID] {
+		if software.ID == softwareID {
+			return software
+		}
+	}
+
+	return nil
+}
+
+func copySoftware(software *Software) Software {
+	s := *software
+	s.Aliases = slices.Clone(software.Aliases)
+
+	return s
+}
+
+// newMemoryID returns a random UUID, like the API's IDs.
+func newMemoryID() string {
+	var b [16]byte
+
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
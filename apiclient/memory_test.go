@@ -0,0 +1,127 @@
+package apiclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryClient() *MemoryClient {
+	clt := NewMemoryClient()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clt.now = func() time.Time {
+		now = now.Add(time.Second)
+
+		return now
+	}
+
+	return clt
+}
+
+func TestMemoryClient_software(t *testing.T) {
+	clt := newTestMemoryClient()
+
+	created, err := clt.PostSoftware(
+		t.Context(), "https://example.org/app", []string{"https://example.org/old"}, "yml", false,
+	)
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.Active)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+	_, err = clt.PostSoftware(t.Context(), "https://example.org/app", nil, "", true)
+	assert.Error(t, err)
+
+	for _, url := range []string{"https://example.org/app", "https://example.org/old"} {
+		found, err := clt.GetSoftwareByURL(t.Context(), url)
+		require.NoError(t, err)
+		require.NotNil(t, found, url)
+		assert.Equal(t, created.ID, found.ID)
+	}
+
+	missing, err := clt.GetSoftwareByURL(t.Context(), "https://example.org/missing")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, clt.PatchSoftware(
+		t.Context(), created.ID, "https://example.org/new", []string{"https://example.org/app"}, "new",
+	))
+
+	patched, err := clt.GetSoftware(t.Context(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org/new", patched.URL)
+	assert.Equal(t, []string{"https://example.org/app"}, patched.Aliases)
+	assert.Equal(t, "new", patched.PubliccodeYml)
+	assert.Equal(t, created.CreatedAt, patched.CreatedAt)
+	assert.True(t, patched.UpdatedAt.After(patched.CreatedAt))
+
+	assert.Error(t, clt.PatchSoftware(t.Context(), "missing", "", nil, ""))
+
+	_, err = clt.GetSoftware(t.Context(), "missing")
+	assert.Error(t, err)
+}
+
+func TestMemoryClient_catalogSoftware(t *testing.T) {
+	clt := newTestMemoryClient()
+
+	created, err := clt.PostCatalogSoftware(t.Context(), "cat", "https://example.org/app", nil, "", true)
+	require.NoError(t, err)
+
+	global, err := clt.GetSoftwareByURL(t.Context(), "https://example.org/app")
+	require.NoError(t, err)
+	assert.Nil(t, global)
+
+	found, err := clt.GetCatalogSoftwareByURL(t.Context(), "cat", "https://example.org/app")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, created.ID, found.ID)
+
+	require.NoError(t, clt.PatchCatalogSoftware(t.Context(), "cat", created.ID, "https://example.org/app", nil, "yml"))
+	assert.Error(t, clt.PatchSoftware(t.Context(), created.ID, "https://example.org/app", nil, "yml"))
+
+	assert.Len(t, clt.Software("cat"), 1)
+	assert.Empty(t, clt.Software(""))
+}
+
+func TestMemoryClient_logs(t *testing.T) {
+	clt := newTestMemoryClient()
+
+	software, err := clt.PostSoftware(t.Context(), "https://example.org/app", nil, "", true)
+	require.NoError(t, err)
+
+	require.NoError(t, clt.PostLog(t.Context(), "general"))
+	require.NoError(t, clt.PostSoftwareLog(t.Context(), software.ID, "software"))
+	require.NoError(t, clt.PostCatalogLog(t.Context(), "cat", "catalog"))
+	assert.Error(t, clt.PostSoftwareLog(t.Context(), "missing", "software"))
+
+	logs := clt.Logs()
+	require.Len(t, logs, 3)
+	assert.Equal(t, Log{Message: "general", CreatedAt: logs[0].CreatedAt}, logs[0])
+	assert.Equal(t, software.ID, logs[1].SoftwareID)
+	assert.Equal(t, "cat", logs[2].CatalogID)
+}
+
+func TestNew(t *testing.T) {
+	t.Cleanup(func() { viper.Set("API_BACKEND", "") })
+
+	viper.Set("API_BACKEND", "memory")
+
+	clt, err := New()
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryClient{}, clt)
+
+	viper.Set("API_BACKEND", "http")
+
+	clt, err = New()
+	require.NoError(t, err)
+	assert.IsType(t, APIClient{}, clt)
+
+	viper.Set("API_BACKEND", "sql")
+
+	_, err = New()
+	assert.Error(t, err)
+}
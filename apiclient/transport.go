@@ -0,0 +1,235 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Options configures how an APIClient talks to the API.
+type Options struct {
+	// RetryMax is how many times a failed request is retried, on connection
+	// errors, 429 and 5xx.
+	RetryMax int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between
+	// retries.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// RetryAfterMax is the longest Retry-After of 429 and 503 replies to
+	// honor, longer ones are cut to it. Zero ignores Retry-After and uses
+	// the backoff.
+	RetryAfterMax time.Duration
+	// Timeout is the timeout of each attempt, zero for none.
+	Timeout time.Duration
+	// BreakerThreshold is how many writes in a row must fail, after their
+	// retries, for the circuit breaker to pause the writes for
+	// BreakerCooldown. Zero disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// OptionsFromConfig returns the Options set with the API_RETRY_MAX,
+// API_RETRY_WAIT_MIN, API_RETRY_WAIT_MAX, API_RETRY_AFTER_MAX, API_TIMEOUT,
+// API_BREAKER_THRESHOLD and API_BREAKER_COOLDOWN settings.
+func OptionsFromConfig() Options {
+	return Options{
+		RetryMax:         viper.GetInt("API_RETRY_MAX"),
+		RetryWaitMin:     viper.GetDuration("API_RETRY_WAIT_MIN"),
+		RetryWaitMax:     viper.GetDuration("API_RETRY_WAIT_MAX"),
+		RetryAfterMax:    viper.GetDuration("API_RETRY_AFTER_MAX"),
+		Timeout:          viper.GetDuration("API_TIMEOUT"),
+		BreakerThreshold: viper.GetInt("API_BREAKER_THRESHOLD"),
+		BreakerCooldown:  viper.GetDuration("API_BREAKER_COOLDOWN"),
+	}
+}
+
+// newHTTPClient returns an http.Client retrying requests as set in opts.
+func newHTTPClient(opts Options) *http.Client {
+	client := retryablehttp.NewClient()
+
+	client.RetryMax = opts.RetryMax
+	client.RetryWaitMin = opts.RetryWaitMin
+	client.RetryWaitMax = opts.RetryWaitMax
+	client.Backoff = backoff(opts.RetryAfterMax)
+	client.HTTPClient.Timeout = opts.Timeout
+
+	return client.StandardClient()
+}
+
+// backoff returns a retryablehttp.Backoff honoring the Retry-After of 429
+// and 503 replies up to retryAfterMax, and backing off exponentially
+// otherwise.
+func backoff(retryAfterMax time.Duration) retryablehttp.Backoff {
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if retryAfterMax > 0 && resp != nil &&
+			(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				return min(wait, retryAfterMax)
+			}
+		}
+
+		return retryablehttp.DefaultBackoff(minWait, maxWait, attemptNum, nil)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP
+// date, into the time to wait from now.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+// breaker is a circuit breaker for the writes to the API: after threshold
+// failures in a row, it opens and makes the writes wait for cooldown, so
+// that a struggling API isn't hammered by the crawler workers. After the
+// pause it's half-open: a single write goes through as a probe while the
+// others keep waiting. The probe succeeding closes the breaker and lets
+// them all through, the probe failing opens it again for cooldown.
+//
+// A nil breaker never pauses.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	until    time.Time
+	// probing is set while the half-open probe is in flight.
+	probing bool
+	// changed is closed, and replaced, when the probe is over.
+	changed chan struct{}
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &breaker{threshold: threshold, cooldown: cooldown, changed: make(chan struct{})}
+}
+
+// wait blocks while the breaker is open, or while another write is probing
+// the API, or until ctx is done. probe is true if the write is the probe,
+// which must be passed to record.
+func (b *breaker) wait(ctx context.Context) (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+
+	for {
+		b.mu.Lock()
+
+		if b.failures < b.threshold {
+			b.mu.Unlock()
+
+			return false, nil
+		}
+
+		var (
+			pause   = time.Until(b.until)
+			changed = b.changed
+		)
+
+		if pause <= 0 && !b.probing {
+			b.probing = true
+			b.mu.Unlock()
+
+			return true, nil
+		}
+
+		b.mu.Unlock()
+
+		// While open wait for the pause to end, while half-open for the
+		// probe to finish.
+		var timer *time.Timer
+
+		var timeout <-chan time.Time
+
+		if pause > 0 {
+			timer = time.NewTimer(pause)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-timeout:
+		case <-changed:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// record counts the outcome of a write: res and err are what the request
+// returned, probe what wait returned.
+func (b *breaker) record(probe bool, res *http.Response, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+
+		defer b.notify()
+	}
+
+	// The caller giving up says nothing about the API, another write will
+	// probe it.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil && res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.threshold && (probe || !time.Now().Before(b.until)) {
+		b.until = time.Now().Add(b.cooldown)
+
+		log.Warnf(
+			"API writes failed %d times in a row, pausing them for %s", b.failures, b.cooldown,
+		)
+	}
+}
+
+// notify wakes the writes waiting for the probe. b.mu must be held.
+func (b *breaker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,246 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() Options {
+	return Options{
+		RetryMax:      2,
+		RetryWaitMin:  time.Millisecond,
+		RetryWaitMax:  time.Millisecond,
+		RetryAfterMax: 50 * time.Millisecond,
+		Timeout:       time.Second,
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Thu, 01 Jan 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 01 Jan 2026 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.header, now)
+		assert.Equal(t, test.ok, ok, test.header)
+		assert.Equal(t, test.want, got, test.header)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	res := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	res.Header.Set("Retry-After", "3")
+
+	assert.Equal(t, 3*time.Second, backoff(time.Minute)(time.Millisecond, time.Second, 0, res))
+	assert.Equal(t, 2*time.Second, backoff(2*time.Second)(time.Millisecond, time.Second, 0, res))
+	assert.Equal(t, time.Millisecond, backoff(0)(time.Millisecond, time.Second, 0, res))
+
+	res.StatusCode = http.StatusInternalServerError
+	assert.Equal(t, time.Millisecond, backoff(time.Minute)(time.Millisecond, time.Second, 0, res))
+}
+
+func TestClientRetriesTooManyRequests(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": [{"id": "1", "url": "https://example.org/app"}]}`))
+	}))
+	defer srv.Close()
+
+	clt := newClient(srv.URL, "token", testOptions())
+
+	software, err := clt.GetSoftwareByURL(t.Context(), "https://example.org/app")
+	require.NoError(t, err)
+	require.NotNil(t, software)
+	assert.Equal(t, "1", software.ID)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	opts := testOptions()
+	opts.RetryMax = 0
+	opts.Timeout = 10 * time.Millisecond
+
+	_, err := newClient(srv.URL, "", opts).GetSoftwareByURL(t.Context(), "https://example.org/app")
+	assert.Error(t, err)
+}
+
+func TestClientContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	err := newClient(srv.URL, "", testOptions()).PostLog(ctx, "message")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBreaker(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	opts := testOptions()
+	opts.RetryMax = 0
+	opts.BreakerThreshold = 2
+	opts.BreakerCooldown = 100 * time.Millisecond
+
+	clt := newClient(srv.URL, "", opts)
+
+	require.Error(t, clt.PostLog(t.Context(), "1"))
+	require.Error(t, clt.PostLog(t.Context(), "2"))
+
+	// Open: writes wait for the cooldown, or until the context is done.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	err := clt.PostLog(ctx, "3")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualValues(t, 2, calls.Load(), "no request while the breaker is open")
+
+	// Reads aren't paused.
+	_, err = clt.GetSoftwareByURL(t.Context(), "https://example.org/app")
+	require.Error(t, err)
+	assert.EqualValues(t, 3, calls.Load())
+
+	healthy.Store(true)
+
+	start := time.Now()
+
+	require.NoError(t, clt.PostLog(t.Context(), "4"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "the write waited for the cooldown")
+
+	start = time.Now()
+
+	require.NoError(t, clt.PostLog(t.Context(), "5"))
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "the breaker closed after a success")
+}
+
+func TestBreaker_halfOpen(t *testing.T) {
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) > 2 {
+			// Hold the probe until the other writes are waiting.
+			<-release
+		}
+
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	opts := testOptions()
+	opts.RetryMax = 0
+	opts.BreakerThreshold = 2
+	opts.BreakerCooldown = 20 * time.Millisecond
+
+	clt := newClient(srv.URL, "", opts)
+
+	require.Error(t, clt.PostLog(t.Context(), "1"))
+	require.Error(t, clt.PostLog(t.Context(), "2"))
+
+	healthy.Store(true)
+
+	errs := make(chan error, 5)
+	for range 5 {
+		go func() { errs <- clt.PostLog(t.Context(), "after the pause") }()
+	}
+
+	assert.Eventually(t, func() bool { return calls.Load() == 3 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 3, calls.Load(), "a single write probes the API while half-open")
+
+	close(release)
+
+	for range 5 {
+		require.NoError(t, <-errs)
+	}
+
+	assert.EqualValues(t, 7, calls.Load())
+}
+
+func TestBreaker_failedProbe(t *testing.T) {
+	b := newBreaker(1, 30*time.Millisecond)
+
+	b.record(false, nil, context.DeadlineExceeded)
+
+	// Half-open after the pause: the probe goes through, a second write
+	// waits for it.
+	probe, err := b.wait(t.Context())
+	require.NoError(t, err)
+	require.True(t, probe)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = b.wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The probe failing opens the breaker again for the cooldown.
+	b.record(true, nil, context.DeadlineExceeded)
+
+	start := time.Now()
+
+	probe, err = b.wait(t.Context())
+	require.NoError(t, err)
+	assert.True(t, probe)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// A cancelled probe hands over to the next write.
+	b.record(true, nil, context.Canceled)
+
+	probe, err = b.wait(t.Context())
+	require.NoError(t, err)
+	assert.True(t, probe)
+}
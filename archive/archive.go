@@ -0,0 +1,237 @@
+// Package archive exports the data in the API to portable NDJSON files, and
+// imports them into another API instance.
+//
+// An archive is a sequence of records, one JSON object per line: a header,
+// then the publishers, the catalogs and the software, each with the data
+// as the API represents it. It's either a single file or a directory with
+// a file per record type, to be read in that order.
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the version of the archive format written by Export.
+const Version = 1
+
+// Record types.
+const (
+	TypeHeader    = "header"
+	TypePublisher = "publisher"
+	TypeCatalog   = "catalog"
+	TypeSoftware  = "software"
+)
+
+// dirFiles are the files of a directory archive, in the order they're read.
+var dirFiles = []struct{ recordType, name string }{
+	{TypePublisher, "publishers.ndjson"},
+	{TypeCatalog, "catalogs.ndjson"},
+	{TypeSoftware, "software.ndjson"},
+}
+
+// Record is a line of an archive.
+type Record struct {
+	Type string `json:"type"`
+	// CatalogID is the catalog of a software record, empty for the software
+	// outside of catalogs.
+	CatalogID string          `json:"catalogId,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Header is the data of the header record.
+type Header struct {
+	Version    int       `json:"version"`
+	Source     string    `json:"source,omitempty"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// Writer writes records to an archive.
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+// Create returns a Writer for a new archive at path: a directory if path
+// ends with a separator or is an existing directory, a single file
+// otherwise.
+func Create(path string) (Writer, error) {
+	if isDirArchive(path) {
+		return newDirWriter(path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't create archive: %w", err)
+	}
+
+	return &fileWriter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+type fileWriter struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+func (w *fileWriter) Write(record Record) error {
+	return w.enc.Encode(record)
+}
+
+func (w *fileWriter) Close() error {
+	return w.file.Close()
+}
+
+// dirWriter writes each record type to its own file, starting each of them
+// with the header.
+type dirWriter struct {
+	files    map[string]*os.File
+	encoders map[string]*json.Encoder
+}
+
+func newDirWriter(dir string) (*dirWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("can't create archive: %w", err)
+	}
+
+	w := &dirWriter{files: map[string]*os.File{}, encoders: map[string]*json.Encoder{}}
+
+	for _, f := range dirFiles {
+		file, err := os.Create(filepath.Join(dir, f.name))
+		if err != nil {
+			w.Close()
+
+			return nil, fmt.Errorf("can't create archive: %w", err)
+		}
+
+		w.files[f.recordType] = file
+		w.encoders[f.recordType] = json.NewEncoder(file)
+	}
+
+	return w, nil
+}
+
+func (w *dirWriter) Write(record Record) error {
+	if record.Type == TypeHeader {
+		for _, enc := range w.encoders {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	enc, ok := w.encoders[record.Type]
+	if !ok {
+		return fmt.Errorf("unknown record type %q", record.Type)
+	}
+
+	return enc.Encode(record)
+}
+
+func (w *dirWriter) Close() error {
+	var errs []error
+
+	for _, file := range w.files {
+		errs = append(errs, file.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Read calls fn for each record of the archive at path, a directory or a
+// single file, after checking its header. It stops at the first error
+// returned by fn.
+func Read(path string, fn func(Record) error) error {
+	if !isDirArchive(path) {
+		return readFile(path, fn)
+	}
+
+	for _, f := range dirFiles {
+		if err := readFile(filepath.Join(path, f.name), fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readFile(path string, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("can't read archive: %w", err)
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+
+	for line := 1; ; line++ {
+		var record Record
+
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			if line == 1 {
+				return fmt.Errorf("%s: empty archive", path)
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: record %d: %w", path, line, err)
+		}
+
+		if line == 1 || record.Type == TypeHeader {
+			if err := checkHeader(record); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+
+			continue
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+func checkHeader(record Record) error {
+	if record.Type != TypeHeader {
+		return errors.New("missing header, not an archive?")
+	}
+
+	var header Header
+	if err := json.Unmarshal(record.Data, &header); err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+
+	if header.Version != Version {
+		return fmt.Errorf("unsupported archive version %d", header.Version)
+	}
+
+	return nil
+}
+
+func isDirArchive(path string) bool {
+	if path != "" && os.IsPathSeparator(path[len(path)-1]) {
+		return true
+	}
+
+	info, err := os.Stat(path)
+
+	return err == nil && info.IsDir()
+}
+
+func newRecord(recordType, catalogID string, data any) (Record, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Record{}, fmt.Errorf("can't encode %s: %w", recordType, err)
+	}
+
+	return Record{Type: recordType, CatalogID: catalogID, Data: raw}, nil
+}
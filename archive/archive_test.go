@@ -0,0 +1,191 @@
+package archive_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI is an in-memory API, assigning sequential IDs.
+type fakeAPI struct {
+	prefix     string
+	nextID     int
+	publishers []apiclient.Publisher
+	catalogs   []apiclient.APICatalog
+	// software by catalog ID, "" for the software outside of catalogs.
+	software map[string][]apiclient.Software
+}
+
+func newFakeAPI(prefix string) *fakeAPI {
+	return &fakeAPI{prefix: prefix, software: map[string][]apiclient.Software{}}
+}
+
+func (api *fakeAPI) id() string {
+	api.nextID++
+
+	return fmt.Sprintf("%s-%d", api.prefix, api.nextID)
+}
+
+func (api *fakeAPI) GetAPIPublishers(context.Context, bool) ([]apiclient.Publisher, error) {
+	return api.publishers, nil
+}
+
+func (api *fakeAPI) PostPublisher(_ context.Context, publisher apiclient.Publisher) (*apiclient.Publisher, error) {
+	publisher.ID = api.id()
+	api.publishers = append(api.publishers, publisher)
+
+	return &publisher, nil
+}
+
+func (api *fakeAPI) GetAPICatalogs(context.Context) ([]apiclient.APICatalog, error) {
+	return api.catalogs, nil
+}
+
+func (api *fakeAPI) PostCatalog(_ context.Context, catalog apiclient.APICatalog) (*apiclient.APICatalog, error) {
+	catalog.ID = api.id()
+	api.catalogs = append(api.catalogs, catalog)
+
+	return &catalog, nil
+}
+
+func (api *fakeAPI) ForEachCatalogSoftware(
+	_ context.Context, catalogID string, _ bool, fn func(apiclient.Software) error,
+) error {
+	for _, software := range api.software[catalogID] {
+		if err := fn(software); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (api *fakeAPI) GetSoftwareByURL(ctx context.Context, url string) (*apiclient.Software, error) {
+	return api.GetCatalogSoftwareByURL(ctx, "", url)
+}
+
+func (api *fakeAPI) GetCatalogSoftwareByURL(_ context.Context, catalogID string, url string) (*apiclient.Software, error) {
+	for _, software := range api.software[catalogID] {
+		if software.URL == url {
+			return &software, nil
+		}
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+func (api *fakeAPI) PostSoftware(
+	ctx context.Context, url string, aliases []string, yml string, active bool,
+) (*apiclient.Software, error) {
+	return api.PostCatalogSoftware(ctx, "", url, aliases, yml, active)
+}
+
+func (api *fakeAPI) PostCatalogSoftware(
+	_ context.Context, catalogID string, url string, aliases []string, yml string, active bool,
+) (*apiclient.Software, error) {
+	software := apiclient.Software{ID: api.id(), URL: url, Aliases: aliases, PubliccodeYml: yml, Active: active}
+	api.software[catalogID] = append(api.software[catalogID], software)
+
+	return &software, nil
+}
+
+func sourceAPI() *fakeAPI {
+	api := newFakeAPI("old")
+	api.publishers = []apiclient.Publisher{
+		{ID: "pub-1", AlternativeID: "c_a", Description: "Comune di A", Active: true,
+			CodeHostings: []apiclient.CodeHosting{{URL: "https://github.com/comune-a", Group: true}}},
+		{ID: "pub-2", Description: "Inactive", Active: false},
+	}
+	api.catalogs = []apiclient.APICatalog{
+		{ID: "cat-1", Name: "Regione", Active: true, Sources: []apiclient.APICatalogSource{
+			{URL: "https://example.org/catalog.json", Args: []string{"path=$.urls"}},
+		}},
+	}
+	api.software[""] = []apiclient.Software{
+		{ID: "sw-1", URL: "https://github.com/comune-a/app", Aliases: []string{"https://github.com/comune-a/old"},
+			PubliccodeYml: "publiccodeYmlVersion: '0.4'", Active: true},
+		{ID: "sw-2", URL: "https://github.com/comune-a/gone", Active: false},
+	}
+	api.software["cat-1"] = []apiclient.Software{
+		{ID: "sw-3", URL: "https://example.org/app", PubliccodeYml: "name: app", Active: true},
+	}
+
+	return api
+}
+
+func TestExportImport(t *testing.T) {
+	for _, name := range []string{"archive.ndjson", "archive" + string(filepath.Separator)} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			w, err := archive.Create(path)
+			require.NoError(t, err)
+
+			stats, err := archive.Export(t.Context(), sourceAPI(), w, "https://api.example.org/v1")
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			assert.Equal(t, archive.Stats{
+				archive.TypePublisher: 2, archive.TypeCatalog: 1, archive.TypeSoftware: 3,
+			}, stats)
+
+			dest := newFakeAPI("new")
+
+			created, ids, err := archive.Import(t.Context(), dest, path)
+			require.NoError(t, err)
+			assert.Equal(t, stats, created)
+
+			require.Len(t, dest.publishers, 2)
+			assert.Equal(t, "c_a", dest.publishers[0].AlternativeID)
+			assert.Equal(t, "pub-2", dest.publishers[1].AlternativeID)
+			assert.False(t, dest.publishers[1].Active)
+			assert.Equal(t, "https://github.com/comune-a", dest.publishers[0].CodeHostings[0].URL)
+
+			require.Len(t, dest.catalogs, 1)
+			assert.Equal(t, "cat-1", dest.catalogs[0].AlternativeID)
+			assert.Equal(t, []string{"path=$.urls"}, dest.catalogs[0].Sources[0].Args)
+
+			newCatalogID := ids[archive.TypeCatalog]["cat-1"]
+			assert.Equal(t, dest.catalogs[0].ID, newCatalogID)
+
+			require.Len(t, dest.software[""], 2)
+			assert.Equal(t, []string{"https://github.com/comune-a/old"}, dest.software[""][0].Aliases)
+			assert.Equal(t, "publiccodeYmlVersion: '0.4'", dest.software[""][0].PubliccodeYml)
+			assert.False(t, dest.software[""][1].Active)
+			require.Len(t, dest.software[newCatalogID], 1)
+			assert.Equal(t, ids[archive.TypeSoftware]["sw-3"], dest.software[newCatalogID][0].ID)
+
+			// Importing again changes nothing.
+			created, idsAgain, err := archive.Import(t.Context(), dest, path)
+			require.NoError(t, err)
+			assert.Empty(t, created)
+			assert.Equal(t, ids, idsAgain)
+			assert.Len(t, dest.publishers, 2)
+			assert.Len(t, dest.software[""], 2)
+		})
+	}
+}
+
+func TestRead_invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"empty.ndjson":     "",
+		"noheader.ndjson":  `{"type":"publisher","data":{}}` + "\n",
+		"version.ndjson":   `{"type":"header","data":{"version":99}}` + "\n",
+		"malformed.ndjson": `{"type":"header","data":{"version":1}}` + "\n{",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		err := archive.Read(path, func(archive.Record) error { return nil })
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"context"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+)
+
+// ExportAPI is the part of the API client Export uses.
+type ExportAPI interface {
+	GetAPIPublishers(ctx context.Context, all bool) ([]apiclient.Publisher, error)
+	GetAPICatalogs(ctx context.Context) ([]apiclient.APICatalog, error)
+	ForEachCatalogSoftware(ctx context.Context, catalogID string, all bool, fn func(apiclient.Software) error) error
+}
+
+// Stats counts the records by type.
+type Stats map[string]int
+
+// Export writes all the publishers, catalogs and software in the API,
+// active and inactive, to w. source identifies the API in the header.
+func Export(ctx context.Context, api ExportAPI, w Writer, source string) (Stats, error) {
+	stats := Stats{}
+
+	write := func(recordType, catalogID string, data any) error {
+		record, err := newRecord(recordType, catalogID, data)
+		if err != nil {
+			return err
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+
+		if recordType != TypeHeader {
+			stats[recordType]++
+		}
+
+		return nil
+	}
+
+	header := Header{Version: Version, Source: source, ExportedAt: time.Now().UTC()}
+	if err := write(TypeHeader, "", header); err != nil {
+		return stats, err
+	}
+
+	publishers, err := api.GetAPIPublishers(ctx, true)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, publisher := range publishers {
+		if err := write(TypePublisher, "", publisher); err != nil {
+			return stats, err
+		}
+	}
+
+	catalogs, err := api.GetAPICatalogs(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, catalog := range catalogs {
+		if err := write(TypeCatalog, "", catalog); err != nil {
+			return stats, err
+		}
+	}
+
+	err = api.ForEachCatalogSoftware(ctx, "", true, func(software apiclient.Software) error {
+		return write(TypeSoftware, "", software)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	for _, catalog := range catalogs {
+		err = api.ForEachCatalogSoftware(ctx, catalog.ID, true, func(software apiclient.Software) error {
+			return write(TypeSoftware, catalog.ID, software)
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
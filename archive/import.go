@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// ImportAPI is the part of the API client Import uses.
+type ImportAPI interface {
+	GetAPIPublishers(ctx context.Context, all bool) ([]apiclient.Publisher, error)
+	PostPublisher(ctx context.Context, publisher apiclient.Publisher) (*apiclient.Publisher, error)
+	GetAPICatalogs(ctx context.Context) ([]apiclient.APICatalog, error)
+	PostCatalog(ctx context.Context, catalog apiclient.APICatalog) (*apiclient.APICatalog, error)
+	GetSoftwareByURL(ctx context.Context, url string) (*apiclient.Software, error)
+	PostSoftware(
+		ctx context.Context, url string, aliases []string, publiccodeYml string, active bool,
+	) (*apiclient.Software, error)
+	GetCatalogSoftwareByURL(ctx context.Context, catalogID string, softwareURL string) (*apiclient.Software, error)
+	PostCatalogSoftware(
+		ctx context.Context, catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
+	) (*apiclient.Software, error)
+}
+
+// IDMap maps, for each record type, the IDs in the archive to the IDs in
+// the API the archive was imported into.
+type IDMap map[string]map[string]string
+
+func (m IDMap) set(recordType, oldID, newID string) {
+	if m[recordType] == nil {
+		m[recordType] = map[string]string{}
+	}
+
+	m[recordType][oldID] = newID
+}
+
+// Import replays the archive at path into the API. The API assigns new IDs,
+// so publishers and catalogs keep their archive IDs as alternative IDs when
+// they don't have one, and the software of catalogs goes to the new catalogs.
+//
+// Records already in the API are skipped: publishers and catalogs with the
+// same alternative ID, software with the same URL. Importing an archive twice
+// is then safe.
+//
+// It returns the records created by type and the ID mapping, which includes
+// the skipped records.
+func Import(ctx context.Context, api ImportAPI, path string) (Stats, IDMap, error) {
+	imp := importer{ctx: ctx, api: api, created: Stats{}, ids: IDMap{}, existing: IDMap{}}
+
+	publishers, err := api.GetAPIPublishers(ctx, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, publisher := range publishers {
+		if publisher.AlternativeID != "" {
+			imp.existing.set(TypePublisher, publisher.AlternativeID, publisher.ID)
+		}
+	}
+
+	catalogs, err := api.GetAPICatalogs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, catalog := range catalogs {
+		if catalog.AlternativeID != "" {
+			imp.existing.set(TypeCatalog, catalog.AlternativeID, catalog.ID)
+		}
+	}
+
+	if err := Read(path, imp.importRecord); err != nil {
+		return imp.created, imp.ids, err
+	}
+
+	return imp.created, imp.ids, nil
+}
+
+type importer struct {
+	ctx     context.Context //nolint:containedctx // lives for one Import call
+	api     ImportAPI
+	created Stats
+	ids     IDMap
+	// existing maps the alternative IDs of the publishers and catalogs
+	// already in the API to their IDs.
+	existing IDMap
+}
+
+func (imp *importer) importRecord(record Record) error {
+	switch record.Type {
+	case TypePublisher:
+		var publisher apiclient.Publisher
+		if err := json.Unmarshal(record.Data, &publisher); err != nil {
+			return fmt.Errorf("invalid publisher record: %w", err)
+		}
+
+		return imp.importPublisher(publisher)
+	case TypeCatalog:
+		var catalog apiclient.APICatalog
+		if err := json.Unmarshal(record.Data, &catalog); err != nil {
+			return fmt.Errorf("invalid catalog record: %w", err)
+		}
+
+		return imp.importCatalog(catalog)
+	case TypeSoftware:
+		var software apiclient.Software
+		if err := json.Unmarshal(record.Data, &software); err != nil {
+			return fmt.Errorf("invalid software record: %w", err)
+		}
+
+		return imp.importSoftware(record.CatalogID, software)
+	default:
+		log.Warnf("skipping unknown archive record type %q", record.Type)
+
+		return nil
+	}
+}
+
+func (imp *importer) importPublisher(publisher apiclient.Publisher) error {
+	if publisher.AlternativeID == "" {
+		publisher.AlternativeID = publisher.ID
+	}
+
+	if id, ok := imp.existing[TypePublisher][publisher.AlternativeID]; ok {
+		log.Debugf("publisher %s already imported as %s", publisher.ID, id)
+		imp.ids.set(TypePublisher, publisher.ID, id)
+
+		return nil
+	}
+
+	created, err := imp.api.PostPublisher(imp.ctx, publisher)
+	if err != nil {
+		return fmt.Errorf("publisher %s: %w", publisher.ID, err)
+	}
+
+	imp.ids.set(TypePublisher, publisher.ID, created.ID)
+	imp.created[TypePublisher]++
+
+	return nil
+}
+
+func (imp *importer) importCatalog(catalog apiclient.APICatalog) error {
+	if catalog.AlternativeID == "" {
+		catalog.AlternativeID = catalog.ID
+	}
+
+	if id, ok := imp.existing[TypeCatalog][catalog.AlternativeID]; ok {
+		log.Debugf("catalog %s already imported as %s", catalog.ID, id)
+		imp.ids.set(TypeCatalog, catalog.ID, id)
+
+		return nil
+	}
+
+	created, err := imp.api.PostCatalog(imp.ctx, catalog)
+	if err != nil {
+		return fmt.Errorf("catalog %s: %w", catalog.ID, err)
+	}
+
+	imp.ids.set(TypeCatalog, catalog.ID, created.ID)
+	imp.created[TypeCatalog]++
+
+	return nil
+}
+
+func (imp *importer) importSoftware(catalogID string, software apiclient.Software) error {
+	var (
+		existing *apiclient.Software
+		err      error
+	)
+
+	newCatalogID := ""
+	if catalogID != "" {
+		var ok bool
+		if newCatalogID, ok = imp.ids[TypeCatalog][catalogID]; !ok {
+			return fmt.Errorf("software %s: catalog %s not in the archive", software.ID, catalogID)
+		}
+
+		existing, err = imp.api.GetCatalogSoftwareByURL(imp.ctx, newCatalogID, software.URL)
+	} else {
+		existing, err = imp.api.GetSoftwareByURL(imp.ctx, software.URL)
+	}
+
+	if err != nil {
+		return fmt.Errorf("software %s: %w", software.ID, err)
+	}
+
+	if existing != nil {
+		imp.ids.set(TypeSoftware, software.ID, existing.ID)
+
+		return nil
+	}
+
+	var created *apiclient.Software
+
+	if newCatalogID != "" {
+		created, err = imp.api.PostCatalogSoftware(
+			imp.ctx, newCatalogID, software.URL, software.Aliases, software.PubliccodeYml, software.Active,
+		)
+	} else {
+		created, err = imp.api.PostSoftware(imp.ctx, software.URL, software.Aliases, software.PubliccodeYml, software.Active)
+	}
+
+	if err != nil {
+		return fmt.Errorf("software %s: %w", software.ID, err)
+	}
+
+	imp.ids.set(TypeSoftware, software.ID, created.ID)
+	imp.created[TypeSoftware]++
+
+	return nil
+}
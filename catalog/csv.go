@@ -0,0 +1,167 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVDriver fetches a CSV file representing a catalog, eg. a spreadsheet
+// export, and reads the URLs from one of its columns.
+type CSVDriver struct {
+	column    string
+	group     string
+	driver    string
+	delimiter rune
+	headers   map[string]string
+}
+
+// NewCSVDriverWithArgs returns a driver configured by args, in the form
+// key=value:
+//
+//   - column=COLUMN, the column with the URLs (default 1);
+//   - group=COLUMN, the column saying whether the entry is an organization
+//     or group ("true" or "false");
+//   - driver=COLUMN, the column with the code hosting driver of the entry;
+//   - delimiter=CHAR, the field delimiter (default ","), or "tab";
+//   - header=NAME:KEY, a request header whose value is the KEY entry in
+//     CREDENTIALS. Can be repeated.
+//
+// COLUMN is either a 1-based column number or a name in the first row.
+// Rows with no valid URL, like the header row, are skipped.
+func NewCSVDriverWithArgs(args []string) (*CSVDriver, error) {
+	driver := &CSVDriver{column: "1", delimiter: ',', headers: map[string]string{}}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("csv driver: invalid argument %q, expected key=value", arg)
+		}
+
+		switch key {
+		case "column":
+			driver.column = value
+		case "group":
+			driver.group = value
+		case "driver":
+			driver.driver = value
+		case "delimiter":
+			if value == "tab" {
+				value = "\t"
+			}
+
+			if utf8.RuneCountInString(value) != 1 {
+				return nil, fmt.Errorf("csv driver: invalid delimiter %q, expected a single character", value)
+			}
+
+			driver.delimiter, _ = utf8.DecodeRuneInString(value)
+		case "header":
+			if err := parseHeaderArg(value, driver.headers); err != nil {
+				return nil, fmt.Errorf("csv driver: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("csv driver: unknown argument %q", key)
+		}
+	}
+
+	return driver, nil
+}
+
+func (c *CSVDriver) Enumerate(ctx context.Context, catalogURL url.URL) ([]Entry, error) {
+	body, _, err := fetch(ctx, catalogURL, c.headers)
+	if err != nil {
+		return nil, fmt.Errorf("csv catalog: %w", err)
+	}
+
+	// Spreadsheet exports often start with a byte order mark.
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.Comma = c.delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv catalog: decode %s: %w", catalogURL.String(), err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	urlColumn, err := columnIndex(c.column, rows[0])
+	if err != nil {
+		return nil, fmt.Errorf("csv catalog: %s: %w", catalogURL.String(), err)
+	}
+
+	groupColumn, driverColumn := -1, -1
+
+	if c.group != "" {
+		if groupColumn, err = columnIndex(c.group, rows[0]); err != nil {
+			return nil, fmt.Errorf("csv catalog: %s: %w", catalogURL.String(), err)
+		}
+	}
+
+	if c.driver != "" {
+		if driverColumn, err = columnIndex(c.driver, rows[0]); err != nil {
+			return nil, fmt.Errorf("csv catalog: %s: %w", catalogURL.String(), err)
+		}
+	}
+
+	entries := make([]Entry, 0, len(rows))
+
+	for _, row := range rows {
+		u := parseURL(field(row, urlColumn))
+		if u == nil {
+			continue
+		}
+
+		entry := Entry{URL: *u}
+
+		if groupColumn >= 0 {
+			entry.Group = asBool(strings.TrimSpace(field(row, groupColumn)))
+		}
+
+		if driverColumn >= 0 {
+			entry.Driver = strings.TrimSpace(field(row, driverColumn))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// columnIndex returns the 0-based index of column, a 1-based column number
+// or a name in the header row.
+func columnIndex(column string, header []string) (int, error) {
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("invalid column number %d", n)
+		}
+
+		return n - 1, nil
+	}
+
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no column named %q", column)
+}
+
+func field(row []string, i int) string {
+	if i >= len(row) {
+		return ""
+	}
+
+	return row[i]
+}
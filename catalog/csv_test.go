@@ -0,0 +1,62 @@
+package catalog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVDriver_columnName(t *testing.T) {
+	catalogURL := fileURL(t, "repos.csv", "\xef\xbb\xbfEnte;Repository;Organizzazione\n"+
+		"Comune di A;https://github.com/comune-a;true\n"+
+		"Comune di B;https://gitlab.com/comune-b/app;false\n"+
+		"Comune di C;;\n")
+
+	driver, err := catalog.NewCSVDriverWithArgs([]string{
+		"column=repository", "group=Organizzazione", "delimiter=;",
+	})
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://github.com/comune-a", "https://gitlab.com/comune-b/app"}, entryURLs(entries))
+	assert.True(t, entries[0].Group)
+	assert.False(t, entries[1].Group)
+}
+
+func TestCSVDriver_columnNumber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("name\turl\tforge\nA\thttps://code.example.org/a/app\tgitea\n"))
+	}))
+	defer srv.Close()
+
+	driver, err := catalog.NewCSVDriverWithArgs([]string{"column=2", "driver=3", "delimiter=tab"})
+	require.NoError(t, err)
+
+	catalogURL, _ := url.Parse(srv.URL)
+
+	entries, err := driver.Enumerate(context.Background(), *catalogURL)
+	require.NoError(t, err)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://code.example.org/a/app", entries[0].URL.String())
+	assert.Equal(t, "gitea", entries[0].Driver)
+}
+
+func TestCSVDriver_invalid(t *testing.T) {
+	_, err := catalog.NewCSVDriverWithArgs([]string{"delimiter=;;"})
+	require.Error(t, err)
+
+	driver, err := catalog.NewCSVDriverWithArgs([]string{"column=missing"})
+	require.NoError(t, err)
+
+	_, err = driver.Enumerate(context.Background(), fileURL(t, "repos.csv", "url\nhttps://github.com/a\n"))
+	require.Error(t, err)
+}
@@ -1,4 +1,4 @@
 // Package catalog provides drivers that enumerate repository URLs from
 // external catalog sources, for example a JSON document exposed by a
-// publisher.
+// publisher or another developers-italia-api instance.
 package catalog
@@ -0,0 +1,26 @@
+package catalog
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+)
+
+// Entry is a location listed by a catalog. Group marks organizations and
+// groups, as opposed to single repositories. An empty Driver means it's
+// inferred from the URL. Args are passed to the driver and Filter selects
+// the repositories of groups, on top of the catalog's own filter.
+type Entry struct {
+	URL    url.URL
+	Group  bool
+	Driver string
+	Args   []string
+	Filter common.RepoFilter
+}
+
+// Enumerator lists the entries in a catalog. The crawler then scans each of
+// them through its code hosting driver.
+type Enumerator interface {
+	Enumerate(ctx context.Context, catalogURL url.URL) ([]Entry, error)
+}
@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/internal"
+)
+
+// maxRedirects is how many redirects fetch follows, as http.Client does.
+const maxRedirects = 10
+
+// fetch returns the content of a catalog and, for HTTP catalogs, the
+// response headers. headers are only sent to the host of catalogURL.
+//
+// Catalogs can also be local files, so that they can be kept in git along
+// with the configuration: file:///abs/path or file:relative/path.
+func fetch(ctx context.Context, catalogURL url.URL, headers map[string]string) ([]byte, http.Header, error) {
+	if catalogURL.Scheme == "file" {
+		path := catalogURL.Path
+		if catalogURL.Opaque != "" {
+			path = catalogURL.Opaque
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %w", catalogURL.String(), err)
+		}
+
+		return data, http.Header{}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, catalogURL.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("new request: %w", err)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := fetchClient(headers).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GET %s: %w", catalogURL.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s: status %d", catalogURL.String(), resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GET %s: %w", catalogURL.String(), err)
+	}
+
+	return data, resp.Header, nil
+}
+
+// fetchClient returns a client dropping headers on redirects to another
+// host: http.Client only drops Authorization and Cookie, not the headers
+// with credentials set by header=NAME:KEY.
+func fetchClient(headers map[string]string) *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+
+			if !sameHost(*req.URL, *via[0].URL) {
+				for name := range headers {
+					req.Header.Del(name)
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// sameHost reports whether a and b are on the same host and port.
+func sameHost(a, b url.URL) bool {
+	return strings.EqualFold(a.Host, b.Host)
+}
+
+// parseHeaderArg parses a header=NAME:KEY argument, adding to headers the
+// header NAME with the KEY entry in CREDENTIALS as value.
+func parseHeaderArg(value string, headers map[string]string) error {
+	name, credentialKey, ok := strings.Cut(value, ":")
+	if !ok || name == "" || credentialKey == "" {
+		return fmt.Errorf("invalid header %q, expected NAME:KEY", value)
+	}
+
+	secret := internal.Credential(credentialKey)
+	if secret == "" {
+		return fmt.Errorf("header %s: no %q entry in CREDENTIALS", name, credentialKey)
+	}
+
+	headers[name] = secret
+
+	return nil
+}
+
+// parseURL returns v as an URL, or nil if it's not a valid absolute one.
+func parseURL(v any) *url.URL {
+	rawURL, ok := v.(string)
+	if !ok {
+		return nil
+	}
+
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil
+	}
+
+	return parsed
+}
@@ -0,0 +1,30 @@
+package catalog_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/stretchr/testify/require"
+)
+
+// fileURL writes content to a temporary file and returns its file:// URL.
+func fileURL(t *testing.T, name, content string) url.URL {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return url.URL{Scheme: "file", Path: path}
+}
+
+func entryURLs(entries []catalog.Entry) []string {
+	urls := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		urls = append(urls, entry.URL.String())
+	}
+
+	return urls
+}
@@ -0,0 +1,289 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/internal"
+	log "github.com/sirupsen/logrus"
+)
+
+// GitRepoDriver reads a catalog maintained as a git repository of YAML
+// files, usually one per publisher, in the publishers.yml format. The
+// sources of all the publishers are the catalog's entries.
+//
+// The entries are cached along with the commit they were read from, so that
+// an unchanged catalog only costs an ls-remote.
+type GitRepoDriver struct {
+	branch   string
+	path     string
+	cacheDir string
+}
+
+// gitRepoCache is the on-disk cache of a git repository catalog.
+type gitRepoCache struct {
+	Commit  string              `json:"commit"`
+	Entries []gitRepoCacheEntry `json:"entries"`
+}
+
+// gitRepoCacheEntry is an Entry, with Filter as include=RULE and
+// exclude=RULE arguments.
+type gitRepoCacheEntry struct {
+	URL    string   `json:"url"`
+	Group  bool     `json:"group"`
+	Driver string   `json:"driver,omitempty"`
+	Args   []string `json:"args,omitempty"`
+	Filter []string `json:"filter,omitempty"`
+}
+
+// NewGitRepoDriverWithArgs returns a driver configured by args, in the form
+// key=value:
+//
+//   - branch=BRANCH, the branch to read (default: the repository's default
+//     branch);
+//   - path=DIR, the directory with the YAML files (default: the whole
+//     repository).
+//
+// The last read commit and its entries are cached in cacheDir.
+func NewGitRepoDriverWithArgs(args []string, cacheDir string) (*GitRepoDriver, error) {
+	driver := &GitRepoDriver{cacheDir: cacheDir}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("gitrepo driver: invalid argument %q, expected key=value", arg)
+		}
+
+		switch key {
+		case "branch":
+			driver.branch = value
+		case "path":
+			cleaned := filepath.Clean(strings.Trim(value, "/"))
+			if !filepath.IsLocal(cleaned) {
+				return nil, fmt.Errorf("gitrepo driver: invalid path %q", value)
+			}
+
+			driver.path = cleaned
+		default:
+			return nil, fmt.Errorf("gitrepo driver: unknown argument %q", key)
+		}
+	}
+
+	return driver, nil
+}
+
+func (c *GitRepoDriver) Enumerate(_ context.Context, catalogURL url.URL) ([]Entry, error) {
+	remote := catalogURL.String()
+
+	branch := c.branch
+	if branch == "" {
+		var err error
+		if branch, err = internal.GitDefaultBranch(remote); err != nil {
+			return nil, fmt.Errorf("gitrepo catalog: %w", err)
+		}
+	}
+
+	commit, err := internal.GitBranchCommit(remote, branch)
+	if err != nil {
+		return nil, fmt.Errorf("gitrepo catalog: %w", err)
+	}
+
+	cachePath := c.cachePath(remote, branch)
+
+	if cache, err := loadGitRepoCache(cachePath); err == nil && cache.Commit == commit {
+		entries, err := cache.entries()
+		if err == nil {
+			log.Debugf("[%s] gitrepo catalog unchanged at %s, using the cached entries", remote, commit)
+
+			return entries, nil
+		}
+
+		log.Warnf("[%s] invalid gitrepo catalog cache, reading the catalog again: %s", remote, err.Error())
+	}
+
+	commit, entries, err := c.read(remote, branch)
+	if err != nil {
+		return nil, fmt.Errorf("gitrepo catalog: %w", err)
+	}
+
+	if err := saveGitRepoCache(cachePath, newGitRepoCache(commit, entries)); err != nil {
+		log.Warnf("[%s] can't cache the gitrepo catalog: %s", remote, err.Error())
+	}
+
+	return entries, nil
+}
+
+// read clones branch of the remote repository and returns its commit and
+// the entries in its YAML files. Files that can't be parsed are skipped.
+func (c *GitRepoDriver) read(remote, branch string) (string, []Entry, error) {
+	tmpDir, err := os.MkdirTemp("", "gitrepo-catalog-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	_, err = internal.RunGit(
+		"", "clone", "--quiet", "--depth=1", "--single-branch", "--branch", branch, "--", remote, tmpDir,
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	out, err := internal.RunGit(tmpDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var entries []Entry
+
+	root := filepath.Join(tmpDir, c.path)
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if ext := filepath.Ext(path); ext != ".yml" && ext != ".yaml" {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(tmpDir, path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		publishers, err := common.ParsePublishers(data)
+		if err != nil {
+			log.Warnf("[%s] %s: %s, skipping", remote, rel, err.Error())
+
+			return nil
+		}
+
+		for _, publisher := range publishers {
+			for _, source := range publisher.Sources {
+				entries = append(entries, Entry{
+					URL:    source.URL,
+					Group:  source.Group,
+					Driver: source.Driver,
+					Args:   source.Args,
+					Filter: source.Filter,
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("can't read %s: %w", c.path, err)
+	}
+
+	return strings.TrimSpace(string(out)), entries, nil
+}
+
+// cachePath returns the cache file for branch of the remote repository,
+// also depending on path since sources can share a repository.
+func (c *GitRepoDriver) cachePath(remote, branch string) string {
+	sum := sha256.Sum256([]byte(remote + "\x00" + branch + "\x00" + c.path))
+
+	return filepath.Join(c.cacheDir, "gitrepo-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+func newGitRepoCache(commit string, entries []Entry) gitRepoCache {
+	cache := gitRepoCache{Commit: commit, Entries: make([]gitRepoCacheEntry, 0, len(entries))}
+
+	for _, entry := range entries {
+		cache.Entries = append(cache.Entries, gitRepoCacheEntry{
+			URL:    entry.URL.String(),
+			Group:  entry.Group,
+			Driver: entry.Driver,
+			Args:   entry.Args,
+			Filter: entry.Filter.Args(),
+		})
+	}
+
+	return cache
+}
+
+// entries returns the cached entries, or an error if any of them can't be
+// restored: a dropped entry or filter rule would change what's crawled.
+func (cache gitRepoCache) entries() ([]Entry, error) {
+	entries := make([]Entry, 0, len(cache.Entries))
+
+	for _, cached := range cache.Entries {
+		u, err := url.Parse(cached.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		filter, rest, err := common.ParseRepoFilterArgs(cached.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("invalid filter %q", rest)
+		}
+
+		entries = append(entries, Entry{
+			URL:    *u,
+			Group:  cached.Group,
+			Driver: cached.Driver,
+			Args:   cached.Args,
+			Filter: filter,
+		})
+	}
+
+	return entries, nil
+}
+
+func loadGitRepoCache(path string) (gitRepoCache, error) {
+	var cache gitRepoCache
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache, err
+	}
+
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return cache, err
+	}
+
+	if cache.Commit == "" {
+		return cache, errors.New("no commit in cache")
+	}
+
+	return cache, nil
+}
+
+func saveGitRepoCache(path string, cache gitRepoCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,194 @@
+package catalog_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.org",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.org",
+	)
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// commitFiles writes files to the repository in dir and commits them.
+func commitFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "--quiet", "-m", "update")
+}
+
+func TestGitRepoDriver(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// The fixture is a local repository.
+	t.Setenv("GIT_ALLOW_PROTOCOL", "file")
+
+	repoDir := t.TempDir()
+	git(t, repoDir, "init", "--quiet", "--initial-branch=main")
+	commitFiles(t, repoDir, map[string]string{
+		"README.md": "# Catalog\n",
+		"publishers/comune-a.yml": `- id: comune-a
+  name: Comune di A
+  orgs:
+    - https://github.com/comune-a
+  repos:
+    - https://gitlab.com/comune-a/app
+`,
+		"publishers/comune-b.yaml": "- id: comune-b\n  repos:\n    - https://github.com/comune-b/app\n",
+		"publishers/broken.yml":    "not: [a list",
+		"other/ignored.yml":        "- id: ignored\n  repos:\n    - https://github.com/ignored/app\n",
+	})
+
+	catalogURL := url.URL{Scheme: "file", Path: repoDir}
+	cacheDir := t.TempDir()
+
+	driver, err := catalog.NewGitRepoDriverWithArgs([]string{"path=publishers"}, cacheDir)
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"https://github.com/comune-a",
+		"https://gitlab.com/comune-a/app",
+		"https://github.com/comune-b/app",
+	}, entryURLs(entries))
+
+	for _, entry := range entries {
+		assert.Equal(t, entry.URL.String() == "https://github.com/comune-a", entry.Group, entry.URL.String())
+	}
+
+	cacheFiles, err := filepath.Glob(filepath.Join(cacheDir, "gitrepo-*.json"))
+	require.NoError(t, err)
+	require.Len(t, cacheFiles, 1)
+
+	cached, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+	assert.Equal(t, entryURLs(entries), entryURLs(cached))
+
+	// New commit: the catalog is read again.
+	commitFiles(t, repoDir, map[string]string{
+		"publishers/comune-c.yml": "- id: comune-c\n  repos:\n    - https://github.com/comune-c/app\n",
+	})
+
+	entries, err = driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+	assert.Contains(t, entryURLs(entries), "https://github.com/comune-c/app")
+	assert.Len(t, entries, 4)
+}
+
+func TestGitRepoDriver_usesCache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// The fixture is a local repository.
+	t.Setenv("GIT_ALLOW_PROTOCOL", "file")
+
+	repoDir := t.TempDir()
+	git(t, repoDir, "init", "--quiet", "--initial-branch=main")
+	commitFiles(t, repoDir, map[string]string{
+		"a.yml": "- id: a\n  repos:\n    - https://github.com/a/app\n",
+	})
+
+	catalogURL := url.URL{Scheme: "file", Path: repoDir}
+	cacheDir := t.TempDir()
+
+	driver, err := catalog.NewGitRepoDriverWithArgs([]string{"branch=main"}, cacheDir)
+	require.NoError(t, err)
+
+	_, err = driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	cacheFiles, err := filepath.Glob(filepath.Join(cacheDir, "gitrepo-*.json"))
+	require.NoError(t, err)
+	require.Len(t, cacheFiles, 1)
+
+	data, err := os.ReadFile(cacheFiles[0])
+	require.NoError(t, err)
+
+	// Tamper with the cached entries: as long as the commit matches, they are
+	// returned without reading the repository.
+	tampered := []byte(strings.Replace(string(data), "https://github.com/a/app", "https://github.com/cached/app", 1))
+	require.NoError(t, os.WriteFile(cacheFiles[0], tampered, 0o600))
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://github.com/cached/app"}, entryURLs(entries))
+}
+
+func TestNewGitRepoDriverWithArgs_invalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"bogus=1"},
+		{"branch"},
+		{"path=../outside"},
+	} {
+		_, err := catalog.NewGitRepoDriverWithArgs(args, t.TempDir())
+		assert.Error(t, err, args)
+	}
+}
+
+func TestGitRepoDriver_filterAndArgs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// The fixture is a local repository.
+	t.Setenv("GIT_ALLOW_PROTOCOL", "file")
+
+	repoDir := t.TempDir()
+	git(t, repoDir, "init", "--quiet", "--initial-branch=main")
+	commitFiles(t, repoDir, map[string]string{
+		"a.yml": `version: 2
+publishers:
+  - id: a
+    sources:
+      - url: https://git.example.org/a
+        group: true
+        driver: gitea
+        args: [token=secret]
+        exclude: ["name:a/legacy-*", archived]
+`,
+	})
+
+	catalogURL := url.URL{Scheme: "file", Path: repoDir}
+
+	driver, err := catalog.NewGitRepoDriverWithArgs(nil, t.TempDir())
+	require.NoError(t, err)
+
+	// Read from the repository, then from the cache.
+	for range 2 {
+		entries, err := driver.Enumerate(context.Background(), catalogURL)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		assert.Equal(t, []string{"token=secret"}, entries[0].Args)
+		assert.Equal(t, []string{"exclude=name:a/legacy-*", "exclude=archived"}, entries[0].Filter.Args())
+	}
+}
@@ -4,71 +4,198 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/PaesslerAG/jsonpath"
+	log "github.com/sirupsen/logrus"
 )
 
+// maxJSONPages caps the pages fetched from a single catalog, as a guard
+// against catalogs whose next page never ends.
+const maxJSONPages = 1000
+
+// nextLinkHeader is the value of the next argument following the next page
+// in the Link header (RFC 8288) instead of in the document.
+const nextLinkHeader = "link"
+
 // JSONDriver fetches a JSON document representing a catalog and extracts
-// repository URLs via a JSONPath expression.
+// its entries via JSONPath expressions.
 type JSONDriver struct {
-	jsonPath string
+	paths   recordPaths
+	next    string
+	headers map[string]string
 }
 
+// NewJSONDriver returns a driver extracting URLs via the jsonPath expression.
 func NewJSONDriver(jsonPath string) *JSONDriver {
-	return &JSONDriver{jsonPath: jsonPath}
+	return &JSONDriver{paths: recordPaths{url: jsonPath}}
 }
 
-func (c *JSONDriver) Enumerate(ctx context.Context, catalogURL url.URL) ([]url.URL, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, catalogURL.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("json catalog: new request: %w", err)
+// NewJSONDriverWithArgs returns a driver configured by args, in the form
+// key=value:
+//
+//   - records=PATH, the records in the document;
+//   - url=PATH, the URL of the entry (required);
+//   - group=PATH, whether the entry is an organization or group
+//     (a boolean, or a string like "true");
+//   - driver=PATH, the code hosting driver of the entry;
+//   - next=PATH, the URL of the next page, or next=link to follow the
+//     Link header;
+//   - header=NAME:KEY, a request header whose value is the KEY entry in
+//     CREDENTIALS. Can be repeated.
+//
+// For compatibility, a single argument starting with "$" is the url path.
+func NewJSONDriverWithArgs(args []string) (*JSONDriver, error) {
+	if len(args) == 1 && strings.HasPrefix(args[0], "$") {
+		return NewJSONDriver(args[0]), nil
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("json catalog: GET %s: %w", catalogURL.String(), err)
+	driver := &JSONDriver{headers: map[string]string{}}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("json driver: invalid argument %q, expected key=value", arg)
+		}
+
+		switch {
+		case driver.paths.set(key, value):
+		case key == "next":
+			driver.next = value
+		case key == "header":
+			if err := parseHeaderArg(value, driver.headers); err != nil {
+				return nil, fmt.Errorf("json driver: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("json driver: unknown argument %q", key)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("json catalog: GET %s: status %d", catalogURL.String(), resp.StatusCode)
+	if err := driver.paths.validate(); err != nil {
+		return nil, fmt.Errorf("json driver: %w", err)
 	}
 
-	var data any
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("json catalog: decode %s: %w", catalogURL.String(), err)
+	return driver, nil
+}
+
+func (c *JSONDriver) Enumerate(ctx context.Context, catalogURL url.URL) ([]Entry, error) {
+	var entries []Entry
+
+	pageURL := &catalogURL
+	seen := map[string]bool{}
+
+	for page := 0; pageURL != nil; page++ {
+		if page == maxJSONPages {
+			log.Warnf("[%s] json catalog: stopping after %d pages", catalogURL.String(), page)
+
+			break
+		}
+
+		if seen[pageURL.String()] {
+			log.Warnf("[%s] json catalog: next page %s already fetched, stopping", catalogURL.String(), pageURL.String())
+
+			break
+		}
+
+		seen[pageURL.String()] = true
+
+		data, header, err := c.get(ctx, catalogURL, *pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pageEntries, err := c.paths.entries(data)
+		if err != nil {
+			return nil, fmt.Errorf("json catalog: %s: %w", pageURL.String(), err)
+		}
+
+		entries = append(entries, pageEntries...)
+
+		nextURL, err := c.nextPage(*pageURL, data, header)
+		if err != nil {
+			return nil, fmt.Errorf("json catalog: %s: %w", pageURL.String(), err)
+		}
+
+		pageURL = nextURL
 	}
 
-	result, err := jsonpath.Get(c.jsonPath, data)
+	return entries, nil
+}
+
+// get fetches the page at pageURL, sending the configured headers only if
+// it's on the host of catalogURL: next pages can point anywhere.
+func (c *JSONDriver) get(ctx context.Context, catalogURL, pageURL url.URL) (any, http.Header, error) {
+	headers := map[string]string{"Accept": "application/json"}
+
+	if sameHost(pageURL, catalogURL) {
+		maps.Copy(headers, c.headers)
+	} else if len(c.headers) > 0 {
+		log.Warnf("[%s] json catalog: not sending the headers to %s, on another host", catalogURL.String(), pageURL.Host)
+	}
+
+	body, header, err := fetch(ctx, pageURL, headers)
 	if err != nil {
-		return nil, fmt.Errorf("json catalog: jsonpath %q on %s: %w", c.jsonPath, catalogURL.String(), err)
+		return nil, nil, fmt.Errorf("json catalog: %w", err)
+	}
+
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, nil, fmt.Errorf("json catalog: decode %s: %w", pageURL.String(), err)
 	}
 
-	var rawURLs []any
-	switch v := result.(type) {
-	case []any:
-		rawURLs = v
+	return data, header, nil
+}
+
+// nextPage returns the URL of the page after pageURL, or nil if it's the last one.
+func (c *JSONDriver) nextPage(pageURL url.URL, data any, header http.Header) (*url.URL, error) {
+	var next string
+
+	switch c.next {
+	case "":
+		return nil, nil //nolint:nilnil
+	case nextLinkHeader:
+		next = nextLink(header.Values("Link"))
 	default:
-		rawURLs = []any{v}
+		// A missing next page is how most APIs signal the last one.
+		raw, _ := jsonpath.Get(c.next, data)
+		next, _ = raw.(string)
 	}
 
-	urls := make([]url.URL, 0, len(rawURLs))
+	if next == "" {
+		return nil, nil //nolint:nilnil
+	}
 
-	for _, raw := range rawURLs {
-		rawURL, ok := raw.(string)
-		if !ok {
-			continue
-		}
+	nextURL, err := pageURL.Parse(next)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next page %q: %w", next, err)
+	}
 
-		parsed, err := url.Parse(rawURL)
-		if err != nil {
-			continue
-		}
+	return nextURL, nil
+}
 
-		urls = append(urls, *parsed)
+// nextLink returns the target of the rel="next" link in Link header values.
+func nextLink(values []string) string {
+	for _, value := range values {
+		for link := range strings.SplitSeq(value, ",") {
+			target, params, _ := strings.Cut(link, ";")
+
+			for param := range strings.SplitSeq(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+
+				for r := range strings.FieldsSeq(strings.Trim(rel, `"`)) {
+					if strings.EqualFold(r, "next") {
+						return strings.Trim(strings.TrimSpace(target), "<>")
+					}
+				}
+			}
+		}
 	}
 
-	return urls, nil
+	return ""
 }
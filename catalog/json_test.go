@@ -0,0 +1,182 @@
+package catalog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func enumerate(t *testing.T, srv *httptest.Server, args []string) []catalog.Entry {
+	t.Helper()
+
+	driver, err := catalog.NewJSONDriverWithArgs(args)
+	require.NoError(t, err)
+
+	catalogURL, err := url.Parse(srv.URL + "/catalog")
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), *catalogURL)
+	require.NoError(t, err)
+
+	return entries
+}
+
+func TestJSONDriver_legacyPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"repos": [{"url": "https://github.com/a/one"}, {"url": "https://github.com/a/two"}]}`))
+	}))
+	defer srv.Close()
+
+	entries := enumerate(t, srv, []string{"$.repos[*].url"})
+
+	assert.Equal(t, []catalog.Entry{
+		{URL: url.URL{Scheme: "https", Host: "github.com", Path: "/a/one"}},
+		{URL: url.URL{Scheme: "https", Host: "github.com", Path: "/a/two"}},
+	}, entries)
+}
+
+func TestJSONDriver_records(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"items": [
+			{"href": "https://github.com/acme", "kind": "org", "isOrg": true},
+			{"href": "https://code.example.org/acme/app", "forge": "gitea", "isOrg": "false"},
+			{"forge": "gitlab"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	entries := enumerate(t, srv, []string{
+		"records=$.items[*]", "url=$.href", "group=$.isOrg", "driver=$.forge",
+	})
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://github.com/acme", entries[0].URL.String())
+	assert.True(t, entries[0].Group)
+	assert.Empty(t, entries[0].Driver)
+	assert.Equal(t, "https://code.example.org/acme/app", entries[1].URL.String())
+	assert.False(t, entries[1].Group)
+	assert.Equal(t, "gitea", entries[1].Driver)
+}
+
+func TestJSONDriver_nextPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"data": ["https://github.com/a/two"], "next": null}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": ["https://github.com/a/one"], "next": "/catalog?page=2"}`))
+	}))
+	defer srv.Close()
+
+	entries := enumerate(t, srv, []string{"url=$.data[*]", "next=$.next"})
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://github.com/a/two", entries[1].URL.String())
+}
+
+func TestJSONDriver_nextLinkAndHeader(t *testing.T) {
+	viper.Set("CREDENTIALS", map[string]string{"catalog-key": "s3cr3t"})
+	t.Cleanup(func() { viper.Set("CREDENTIALS", nil) })
+
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`["https://github.com/a/two"]`))
+
+			return
+		}
+
+		w.Header().Set("Link", `<`+srv.URL+`/catalog?page=1>; rel="prev", <`+srv.URL+`/catalog?page=2>; rel="next"`)
+		_, _ = w.Write([]byte(`["https://github.com/a/one"]`))
+	}))
+	defer srv.Close()
+
+	entries := enumerate(t, srv, []string{"url=$[*]", "next=link", "header=X-Api-Key:catalog-key"})
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://github.com/a/one", entries[0].URL.String())
+	assert.Equal(t, "https://github.com/a/two", entries[1].URL.String())
+}
+
+// The credentials in the headers aren't sent to other hosts, be it through
+// the next page or a redirect.
+func TestJSONDriver_headerOtherHost(t *testing.T) {
+	viper.Set("CREDENTIALS", map[string]string{"catalog-key": "s3cr3t"})
+	t.Cleanup(func() { viper.Set("CREDENTIALS", nil) })
+
+	var leaked atomic.Int32
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "" {
+			leaked.Add(1)
+		}
+
+		_, _ = w.Write([]byte(`{"data": ["https://github.com/b/app"]}`))
+	}))
+	defer other.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Redirect(w, r, other.URL+"/redirected", http.StatusFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": ["https://github.com/a/app"], "next": "?page=2"}`))
+	}))
+	defer srv.Close()
+
+	args := []string{"url=$.data[*]", "next=$.next", "header=X-Api-Key:catalog-key"}
+	assert.Len(t, enumerate(t, srv, args), 2)
+
+	next := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": ["https://github.com/a/app"], "next": "` + other.URL + `/next"}`))
+	}))
+	defer next.Close()
+
+	assert.Len(t, enumerate(t, next, args), 2)
+	assert.Zero(t, leaked.Load())
+}
+
+func TestJSONDriver_nextLoop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": ["https://github.com/a/one"], "next": "/catalog"}`))
+	}))
+	defer srv.Close()
+
+	entries := enumerate(t, srv, []string{"url=$.data[*]", "next=$.next"})
+
+	assert.Len(t, entries, 1)
+}
+
+func TestNewJSONDriverWithArgs_invalid(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"records=$.items[*]"},
+		{"url=$.x", "bogus=1"},
+		{"url"},
+		{"url=$.x", "group=$.g"},
+		{"url=$.x", "header=X-Api-Key"},
+		{"url=$.x", "header=X-Api-Key:missing"},
+	} {
+		_, err := catalog.NewJSONDriverWithArgs(args)
+		assert.Error(t, err, args)
+	}
+}
@@ -0,0 +1,128 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// recordPaths are the JSONPath expressions selecting the entries in a
+// structured (JSON or YAML) catalog document.
+//
+// With no records path, the url path is evaluated on the whole document and
+// must return the URLs. Otherwise the records path selects the records and
+// the url, group and driver paths are evaluated on each of them.
+type recordPaths struct {
+	records string
+	url     string
+	group   string
+	driver  string
+}
+
+// set sets the path for the records, url, group or driver argument, and
+// reports whether key is one of them.
+func (p *recordPaths) set(key, value string) bool {
+	switch key {
+	case "records":
+		p.records = value
+	case "url":
+		p.url = value
+	case "group":
+		p.group = value
+	case "driver":
+		p.driver = value
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (p recordPaths) validate() error {
+	if p.url == "" {
+		return errors.New("missing the url argument")
+	}
+
+	if p.records == "" && (p.group != "" || p.driver != "") {
+		return errors.New("the group and driver arguments need the records argument")
+	}
+
+	return nil
+}
+
+// entries extracts the entries from a decoded document. Records with an
+// invalid or missing URL are skipped.
+func (p recordPaths) entries(data any) ([]Entry, error) {
+	if p.records == "" {
+		result, err := jsonpath.Get(p.url, data)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath %q: %w", p.url, err)
+		}
+
+		var entries []Entry
+
+		for _, raw := range asSlice(result) {
+			if u := parseURL(raw); u != nil {
+				entries = append(entries, Entry{URL: *u})
+			}
+		}
+
+		return entries, nil
+	}
+
+	result, err := jsonpath.Get(p.records, data)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath %q: %w", p.records, err)
+	}
+
+	records := asSlice(result)
+	entries := make([]Entry, 0, len(records))
+
+	for _, record := range records {
+		raw, _ := jsonpath.Get(p.url, record)
+
+		u := parseURL(raw)
+		if u == nil {
+			continue
+		}
+
+		entry := Entry{URL: *u}
+
+		if p.group != "" {
+			raw, _ := jsonpath.Get(p.group, record)
+			entry.Group = asBool(raw)
+		}
+
+		if p.driver != "" {
+			raw, _ := jsonpath.Get(p.driver, record)
+			entry.Driver, _ = raw.(string)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func asSlice(v any) []any {
+	if s, ok := v.([]any); ok {
+		return s
+	}
+
+	return []any{v}
+}
+
+func asBool(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		parsed, _ := strconv.ParseBool(b)
+
+		return parsed
+	default:
+		return false
+	}
+}
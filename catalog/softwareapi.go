@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// SoftwareAPIDriver reads the software of another developers-italia-api
+// compatible instance, so that regional catalogs can federate into this one.
+//
+// As an Enumerator it returns the software URLs, which the crawler scans as
+// any other repository. As a Lister it imports the upstream publiccode.yml
+// files as they are, without going to the code hosting platforms.
+type SoftwareAPIDriver struct {
+	filter common.RepoFilter
+}
+
+func NewSoftwareAPIDriver() *SoftwareAPIDriver {
+	return &SoftwareAPIDriver{}
+}
+
+// WithFilter returns a copy of the driver listing only the repositories
+// matching filter. Only rules on names apply, as the API has no other
+// information on the repositories.
+func (d *SoftwareAPIDriver) WithFilter(filter common.RepoFilter) *SoftwareAPIDriver {
+	return &SoftwareAPIDriver{filter: filter}
+}
+
+func (*SoftwareAPIDriver) Enumerate(ctx context.Context, apiURL url.URL) ([]Entry, error) {
+	var entries []Entry
+
+	upstream := apiclient.NewUpstreamClient(apiURL.String())
+
+	err := upstream.ForEachSoftware(ctx, func(software apiclient.Software) error {
+		parsed, err := url.Parse(software.URL)
+		if err != nil {
+			log.Warnf("[%s] software %s: invalid url %q, skipping", apiURL.String(), software.ID, software.URL)
+
+			return nil
+		}
+
+		entries = append(entries, Entry{URL: *parsed})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("software-catalog-api: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (d *SoftwareAPIDriver) List(
+	apiURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	upstream := apiclient.NewUpstreamClient(apiURL.String())
+
+	err := upstream.ForEachSoftware(context.Background(), func(software apiclient.Software) error {
+		if software.PubliccodeYml == "" {
+			log.Warnf("[%s] software %s has no publiccode.yml, skipping", apiURL.String(), software.ID)
+
+			return nil
+		}
+
+		parsed, err := url.Parse(software.URL)
+		if err != nil {
+			log.Warnf("[%s] software %s: invalid url %q, skipping", apiURL.String(), software.ID, software.URL)
+
+			return nil
+		}
+
+		name := strings.Trim(strings.TrimSuffix(parsed.Path, ".git"), "/")
+
+		if !d.filter.Match(common.RepoInfo{Name: name}) {
+			log.Debugf("[%s] skipping %s, not matching the source filters", apiURL.String(), software.URL)
+
+			return nil
+		}
+
+		repositories <- common.Repository{
+			Name:         name,
+			FileContent:  []byte(software.PubliccodeYml),
+			URL:          *parsed,
+			CanonicalURL: *parsed,
+			Publisher:    publisher,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("software-catalog-api: %w", err)
+	}
+
+	return nil
+}
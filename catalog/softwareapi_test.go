@@ -0,0 +1,102 @@
+package catalog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpstreamAPI(t *testing.T) *url.URL {
+	t.Helper()
+
+	pages := map[string]map[string]any{
+		"": {
+			"data": []map[string]any{
+				{"id": "1", "url": "https://github.com/acme/one", "publiccodeYml": "publiccodeYmlVersion: '0.4'"},
+				{"id": "2", "url": "https://gitlab.com/acme/two.git", "publiccodeYml": ""},
+			},
+			"links": map[string]string{"next": "?page[after]=2"},
+		},
+		"2": {
+			"data": []map[string]any{
+				{"id": "3", "url": "https://code.example.org/acme/three", "publiccodeYml": "name: three"},
+			},
+			"links": map[string]string{"next": ""},
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/software" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		page, ok := pages[r.URL.Query().Get("page[after]")]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(srv.Close)
+
+	apiURL, err := url.Parse(srv.URL + "/v1")
+	require.NoError(t, err)
+
+	return apiURL
+}
+
+func TestSoftwareAPIDriver_Enumerate(t *testing.T) {
+	apiURL := newUpstreamAPI(t)
+
+	entries, err := catalog.NewSoftwareAPIDriver().Enumerate(context.Background(), *apiURL)
+	require.NoError(t, err)
+
+	got := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		assert.False(t, entry.Group)
+		got = append(got, entry.URL.String())
+	}
+
+	assert.Equal(t, []string{
+		"https://github.com/acme/one",
+		"https://gitlab.com/acme/two.git",
+		"https://code.example.org/acme/three",
+	}, got)
+}
+
+func TestSoftwareAPIDriver_List(t *testing.T) {
+	apiURL := newUpstreamAPI(t)
+	publisher := common.Publisher{ID: "regione", Name: "Regione"}
+
+	repos := make(chan common.Repository, 10)
+	require.NoError(t, catalog.NewSoftwareAPIDriver().List(*apiURL, publisher, repos))
+	close(repos)
+
+	var got []common.Repository
+	for repo := range repos {
+		got = append(got, repo)
+	}
+
+	// Software with no publiccode.yml upstream is skipped.
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "acme/one", got[0].Name)
+	assert.Equal(t, "https://github.com/acme/one", got[0].CanonicalURL.String())
+	assert.Equal(t, []byte("publiccodeYmlVersion: '0.4'"), got[0].FileContent)
+	assert.Empty(t, got[0].FileRawURL)
+	assert.Equal(t, publisher, got[0].Publisher)
+
+	assert.Equal(t, "acme/three", got[1].Name)
+}
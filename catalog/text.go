@@ -0,0 +1,69 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TextDriver fetches a plain text file representing a catalog, with one URL
+// per line. Empty lines and lines starting with "#" are ignored.
+type TextDriver struct {
+	headers map[string]string
+}
+
+// NewTextDriverWithArgs returns a driver configured by args, in the form
+// header=NAME:KEY to add a request header.
+func NewTextDriverWithArgs(args []string) (*TextDriver, error) {
+	driver := &TextDriver{headers: map[string]string{}}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key != "header" {
+			return nil, fmt.Errorf("text driver: unknown argument %q", arg)
+		}
+
+		if err := parseHeaderArg(value, driver.headers); err != nil {
+			return nil, fmt.Errorf("text driver: %w", err)
+		}
+	}
+
+	return driver, nil
+}
+
+func (c *TextDriver) Enumerate(ctx context.Context, catalogURL url.URL) ([]Entry, error) {
+	body, _, err := fetch(ctx, catalogURL, c.headers)
+	if err != nil {
+		return nil, fmt.Errorf("text catalog: %w", err)
+	}
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		u := parseURL(line)
+		if u == nil {
+			log.Warnf("[%s] text catalog: line %d: invalid URL %q, skipping", catalogURL.String(), n, line)
+
+			continue
+		}
+
+		entries = append(entries, Entry{URL: *u})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("text catalog: read %s: %w", catalogURL.String(), err)
+	}
+
+	return entries, nil
+}
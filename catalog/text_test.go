@@ -0,0 +1,47 @@
+package catalog_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextDriver(t *testing.T) {
+	catalogURL := fileURL(t, "repos.txt", `# Comune di A
+https://github.com/comune-a/app
+
+  https://gitlab.com/comune-b/app
+not an url
+`)
+
+	driver, err := catalog.NewTextDriverWithArgs(nil)
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://github.com/comune-a/app", "https://gitlab.com/comune-b/app"}, entryURLs(entries))
+}
+
+func TestTextDriver_relativeFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repos.txt"), []byte("https://github.com/a/b\n"), 0o600))
+	t.Chdir(dir)
+
+	catalogURL, err := url.Parse("file:repos.txt")
+	require.NoError(t, err)
+
+	driver, err := catalog.NewTextDriverWithArgs(nil)
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), *catalogURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://github.com/a/b"}, entryURLs(entries))
+}
@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLDriver fetches a YAML document representing a catalog and extracts
+// its entries via JSONPath expressions, the same way as JSONDriver.
+type YAMLDriver struct {
+	paths   recordPaths
+	headers map[string]string
+}
+
+// NewYAMLDriverWithArgs returns a driver configured by args, in the form
+// key=value: records, url, group and driver select the entries as in
+// NewJSONDriverWithArgs, header=NAME:KEY adds a request header.
+//
+// A single argument starting with "$" is the url path.
+func NewYAMLDriverWithArgs(args []string) (*YAMLDriver, error) {
+	driver := &YAMLDriver{headers: map[string]string{}}
+
+	if len(args) == 1 && strings.HasPrefix(args[0], "$") {
+		driver.paths.url = args[0]
+
+		return driver, nil
+	}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("yaml driver: invalid argument %q, expected key=value", arg)
+		}
+
+		switch {
+		case driver.paths.set(key, value):
+		case key == "header":
+			if err := parseHeaderArg(value, driver.headers); err != nil {
+				return nil, fmt.Errorf("yaml driver: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("yaml driver: unknown argument %q", key)
+		}
+	}
+
+	if err := driver.paths.validate(); err != nil {
+		return nil, fmt.Errorf("yaml driver: %w", err)
+	}
+
+	return driver, nil
+}
+
+func (c *YAMLDriver) Enumerate(ctx context.Context, catalogURL url.URL) ([]Entry, error) {
+	body, _, err := fetch(ctx, catalogURL, c.headers)
+	if err != nil {
+		return nil, fmt.Errorf("yaml catalog: %w", err)
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("yaml catalog: decode %s: %w", catalogURL.String(), err)
+	}
+
+	// Round-trip through JSON, so that the JSONPath expressions see the
+	// same types as in JSON documents.
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("yaml catalog: decode %s: %w", catalogURL.String(), err)
+	}
+
+	var data any
+	if err := json.Unmarshal(normalized, &data); err != nil {
+		return nil, fmt.Errorf("yaml catalog: decode %s: %w", catalogURL.String(), err)
+	}
+
+	entries, err := c.paths.entries(data)
+	if err != nil {
+		return nil, fmt.Errorf("yaml catalog: %s: %w", catalogURL.String(), err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,44 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLDriver(t *testing.T) {
+	catalogURL := fileURL(t, "catalog.yml", `
+software:
+  - name: one
+    repo: https://github.com/acme/one
+  - name: org
+    repo: https://gitlab.com/acme
+    org: true
+  - name: no-url
+`)
+
+	driver, err := catalog.NewYAMLDriverWithArgs([]string{"records=$.software[*]", "url=$.repo", "group=$.org"})
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://github.com/acme/one", "https://gitlab.com/acme"}, entryURLs(entries))
+	assert.False(t, entries[0].Group)
+	assert.True(t, entries[1].Group)
+}
+
+func TestYAMLDriver_legacyPath(t *testing.T) {
+	catalogURL := fileURL(t, "catalog.yml", "- https://github.com/acme/one\n- https://github.com/acme/two\n")
+
+	driver, err := catalog.NewYAMLDriverWithArgs([]string{"$[*]"})
+	require.NoError(t, err)
+
+	entries, err := driver.Enumerate(context.Background(), catalogURL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://github.com/acme/one", "https://github.com/acme/two"}, entryURLs(entries))
+}
@@ -25,7 +25,7 @@ Crawl a single software given its API id and its publisher.`,
 		" https://api.developers.italia.it/v1/software/af6056fc-b2b2-4d31-9961-c9bd94e32bd4 PCM",
 
 	Args: cobra.ExactArgs(2),
-	Run: func(_ *cobra.Command, args []string) {
+	Run: func(cmd *cobra.Command, args []string) {
 		if token := viper.GetString("GITHUB_TOKEN"); token == "" {
 			log.Fatal("Please set GITHUB_TOKEN, it's needed to use the GitHub API'")
 		}
@@ -36,7 +36,7 @@ Crawl a single software given its API id and its publisher.`,
 			ID: args[1],
 		}
 
-		if err := crwlr.CrawlSoftwareByID(args[0], publisher); err != nil {
+		if err := crwlr.CrawlSoftwareByID(cmd.Context(), args[0], publisher); err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"context"
+
 	"github.com/italia/publiccode-crawler/v4/common"
 	"github.com/italia/publiccode-crawler/v4/crawler"
 	log "github.com/sirupsen/logrus"
@@ -33,7 +34,7 @@ crawl publishers.yml
 crawl directory/*.yml`,
 
 	Args: cobra.MinimumNArgs(0),
-	Run: func(_ *cobra.Command, args []string) {
+	Run: func(cmd *cobra.Command, args []string) {
 		if token := viper.GetString("GITHUB_TOKEN"); token == "" {
 			log.Fatal("Please set GITHUB_TOKEN, it's needed to use the GitHub API'")
 		}
@@ -41,25 +42,25 @@ crawl directory/*.yml`,
 		crwlr := crawler.NewCrawler(dryRun)
 
 		if len(args) > 0 {
-			crawlFromYAML(crwlr, args)
+			crawlFromYAML(cmd.Context(), crwlr, args)
 
 			return
 		}
 
-		crawlFromAPI(crwlr)
+		crawlFromAPI(cmd.Context(), crwlr)
 	},
 }
 
-func crawlFromAPI(crwlr *crawler.Crawler) {
-	client := apiclient.NewClient()
+func crawlFromAPI(ctx context.Context, crwlr *crawler.Crawler) {
+	client := crwlr.APIClient()
 
-	catalogs, err := client.GetCatalogs()
+	catalogs, err := client.GetCatalogs(ctx)
 	if err != nil {
 		log.Warnf("Failed to get catalogs: %s, falling back to publishers", err)
 	}
 
 	if len(catalogs) > 0 {
-		if err := crwlr.CrawlCatalogs(catalogs); err != nil {
+		if err := crwlr.CrawlCatalogs(ctx, catalogs); err != nil {
 			log.Fatal(err)
 		}
 
@@ -68,17 +69,17 @@ func crawlFromAPI(crwlr *crawler.Crawler) {
 
 	log.Info("No catalogs found, falling back to publishers")
 
-	publishers, err := client.GetPublishers()
+	publishers, err := client.GetPublishers(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := crwlr.CrawlPublishers(publishers); err != nil {
+	if err := crwlr.CrawlPublishers(ctx, publishers); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func crawlFromYAML(crwlr *crawler.Crawler, args []string) {
+func crawlFromYAML(ctx context.Context, crwlr *crawler.Crawler, args []string) {
 	var publishers []common.Publisher
 
 	for _, yamlFile := range args {
@@ -90,7 +91,7 @@ func crawlFromYAML(crwlr *crawler.Crawler, args []string) {
 		publishers = append(publishers, filePublishers...)
 	}
 
-	if err := crwlr.CrawlPublishers(publishers); err != nil {
+	if err := crwlr.CrawlPublishers(ctx, publishers); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -1,102 +1,143 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/publishers"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+)
+
+var (
+	repolistFormat string
+	prune          bool
 )
 
 func init() {
-	rootCmd.AddCommand(downloadPublishersCmd)
-}
+	downloadPublishersCmd.Flags().StringVarP(
+		&repolistFormat, "format", "f", "",
+		"format of the list: yaml, json or csv (default: from the file extension, or yaml)",
+	)
+	downloadPublishersCmd.Flags().BoolVar(&prune, "prune", false, "remove the publishers and sources no longer in the list")
+	downloadPublishersCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "only print the changes, without writing DEST_FILE")
 
-type repolistType struct {
-	Registrati []struct {
-		IPA string `yaml:"ipa"`
-		URL string `yaml:"url"`
-		PEC string `yaml:"pec"`
-	} `yaml:"registrati"`
+	rootCmd.AddCommand(downloadPublishersCmd)
 }
 
 var downloadPublishersCmd = &cobra.Command{
 	Use:   "download-publishers REPOLIST_URL DEST_FILE",
 	Short: "Download the list of repos and orgs from the onboarding portal.",
-	Long:  `Download the list of repos and orgs from the onboarding portal and convert it into a publishers.yml.`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Download the list of repos and orgs from the onboarding portal and convert it into a publishers.yml.
+
+If DEST_FILE exists, the list is merged into it: sources are added to the
+publishers with the same IPA code, unless they already have them. With
+--prune, the publishers and sources no longer in the list are removed.
+
+The changes are printed before DEST_FILE is written.
+REPOLIST_URL can also be a local file.`,
+	Example: `
+# Preview the changes to publishers.yml
+download-publishers --dry-run https://example.org/repolist.yml publishers.yml
+
+# Merge a CSV export, removing the publishers that left
+download-publishers --prune --format csv repolist.csv publishers.yml`,
+	Args: cobra.ExactArgs(2),
 	Run: func(_ *cobra.Command, args []string) {
-		var publishers []common.Publisher
+		var existing []common.Publisher
+
 		if _, err := os.Stat(args[1]); err == nil {
-			data, err := os.ReadFile(args[1])
-			if err != nil {
-				log.Fatalf("error in reading %s: %v", args[1], err)
+			if existing, err = common.LoadPublishers(args[1]); err != nil {
+				log.Fatal(err)
 			}
-			//nolint:musttag // false positive
-			_ = yaml.Unmarshal(data, &publishers)
 		}
 
-		resp, err := http.Get(args[0])
+		data, err := readRepolist(args[0])
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer resp.Body.Close()
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatal(err)
+
+		format := repolistFormat
+		if format == "" {
+			format = repolistFormatFromName(args[0])
 		}
 
-		var repolist repolistType
-		err = yaml.Unmarshal(bodyBytes, &repolist)
+		regs, err := publishers.ParseRegistrations(data, format)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("%s: %s", args[0], err.Error())
 		}
 
-	REPOLIST:
-		for _, entry := range repolist.Registrati {
-			for idx, publisher := range publishers {
-				if publisher.ID == entry.IPA {
-					parsedURL, _ := url.Parse(entry.URL)
-					// If this Id is already known, append this URL to the existing item
-					publishers[idx].Sources = append(publisher.Sources, common.CodeHosting{
-						URL:    *parsedURL,
-						Driver: common.InferVCSDriver(*parsedURL),
-						Group:  true,
-					})
-
-					continue REPOLIST
-				}
-			}
+		merged, changes := publishers.Merge(existing, regs, prune)
 
-			parsedURL, _ := url.Parse(entry.URL)
-			// If this IPA code is not known, append a new publisher item
-			publishers = append(publishers, common.Publisher{
-				Name: entry.IPA,
-				ID:   entry.IPA,
-				Sources: []common.CodeHosting{{
-					URL:    *parsedURL,
-					Driver: common.InferVCSDriver(*parsedURL),
-					Group:  true,
-				}},
-			})
+		for _, change := range changes {
+			//nolint:forbidigo
+			fmt.Println(change.String())
 		}
 
-		// Write to the destination file
-		outFile, err := os.Create(args[1])
-		if err != nil {
-			log.Fatal(err)
+		if len(changes) == 0 {
+			log.Infof("%s is up to date", args[1])
+		}
+
+		if dryRun {
+			return
 		}
-		defer outFile.Close()
-		data, err := yaml.Marshal(publishers)
+
+		out, err := common.MarshalPublishers(merged)
 		if err != nil {
 			log.Fatal(err)
 		}
-		if _, err = outFile.Write(data); err != nil {
+
+		if err := os.WriteFile(args[1], out, 0o644); err != nil { //nolint:gosec // not a secret
 			log.Fatal(err)
 		}
 	},
 }
+
+// readRepolist returns the content of the list at location, an HTTP URL or
+// a local file.
+func readRepolist(location string) ([]byte, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return os.ReadFile(location)
+	}
+
+	resp, err := http.Get(location) //nolint:noctx // one-off command
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: HTTP status %s", location, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", location, err)
+	}
+
+	return data, nil
+}
+
+// repolistFormatFromName returns the format of the list at location from its
+// extension, defaulting to YAML.
+func repolistFormatFromName(location string) string {
+	if u, err := url.Parse(location); err == nil && u.Path != "" {
+		location = u.Path
+	}
+
+	switch strings.ToLower(path.Ext(location)) {
+	case ".json":
+		return publishers.FormatJSON
+	case ".csv":
+		return publishers.FormatCSV
+	default:
+		return publishers.FormatYAML
+	}
+}
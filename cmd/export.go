@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/archive"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var idMapFile string
+
+func init() {
+	importCmd.Flags().StringVar(&idMapFile, "id-map", "", "write the mapping from the archive IDs to the new IDs to this JSON file")
+
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export ARCHIVE",
+	Short: "Export the publishers, catalogs and software in the API.",
+	Long: `Export the publishers, catalogs and software in the API, active and
+inactive, to an NDJSON archive.
+
+ARCHIVE is a directory, with a file per record type, if it ends with a
+slash or already exists as a directory, a single file otherwise.`,
+	Example: `
+# Export to a single file
+export backup.ndjson
+
+# Export to a directory
+export backup/`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := archive.Create(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		stats, err := archive.Export(cmd.Context(), apiclient.NewClient(), w, viper.GetString("API_BASEURL"))
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Infof(
+			"exported %d publishers, %d catalogs and %d software to %s",
+			stats[archive.TypePublisher], stats[archive.TypeCatalog], stats[archive.TypeSoftware], args[0],
+		)
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import ARCHIVE",
+	Short: "Import an archive made with export into the API.",
+	Long: `Import an archive made with export into the API.
+
+The API assigns new IDs: publishers and catalogs keep the old ones as
+alternativeId when they don't have one, and --id-map saves the mapping.
+Publishers and catalogs with the same alternativeId and software with the
+same URL already in the API are skipped, so an import can be run again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		created, ids, err := archive.Import(cmd.Context(), apiclient.NewClient(), args[0])
+
+		log.Infof(
+			"imported %d publishers, %d catalogs and %d software",
+			created[archive.TypePublisher], created[archive.TypeCatalog], created[archive.TypeSoftware],
+		)
+
+		if idMapFile != "" && ids != nil {
+			data, jsonErr := json.MarshalIndent(ids, "", "  ")
+			if jsonErr != nil {
+				log.Fatal(jsonErr)
+			}
+
+			if writeErr := os.WriteFile(idMapFile, data, 0o644); writeErr != nil { //nolint:gosec // not a secret
+				log.Fatal(writeErr)
+			}
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
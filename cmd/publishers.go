@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/publishers"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	lintOnline        bool
+	lintFormat        string
+	deactivateMissing bool
+)
+
+func init() {
+	publishersLintCmd.Flags().BoolVar(&lintOnline, "online", false, "check that each source resolves through its driver")
+	publishersLintCmd.Flags().StringVarP(&lintFormat, "format", "f", "text", "output format: text or json")
+
+	publishersPushCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "only print the changes, without making them")
+	publishersPushCmd.Flags().BoolVar(
+		&deactivateMissing, "deactivate-missing", false, "deactivate the API publishers missing from the files",
+	)
+
+	publishersCmd.AddCommand(publishersUpgradeCmd)
+	publishersCmd.AddCommand(publishersLintCmd)
+	publishersCmd.AddCommand(publishersPushCmd)
+
+	rootCmd.AddCommand(publishersCmd)
+}
+
+var publishersCmd = &cobra.Command{
+	Use:   "publishers",
+	Short: "Manage publishers YAML files.",
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := cmd.Help(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var publishersUpgradeCmd = &cobra.Command{
+	Use:   "upgrade SRC_FILE [DEST_FILE]",
+	Short: "Convert a publishers YAML file to the latest format.",
+	Long: `Convert a publishers YAML file to the latest format.
+
+Drivers inferred from the URLs are written explicitly. The result is
+written to DEST_FILE, which can be SRC_FILE itself, or to stdout.`,
+	Example: `
+# Print the upgraded file
+publishers upgrade publishers.yml
+
+# Upgrade the file in place
+publishers upgrade publishers.yml publishers.yml`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(_ *cobra.Command, args []string) {
+		publishers, err := common.LoadPublishers(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		data, err := common.MarshalPublishers(publishers)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(args) < 2 {
+			if _, err := os.Stdout.Write(data); err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+
+		if err := os.WriteFile(args[1], data, 0o644); err != nil { //nolint:gosec // not a secret
+			log.Fatal(err)
+		}
+	},
+}
+
+var publishersLintCmd = &cobra.Command{
+	Use:   "lint [publishers.yml ...]",
+	Short: "Check publishers for mistakes.",
+	Long: `Check publishers for mistakes.
+
+When run with no arguments, the publishers are fetched from the API,
+otherwise the passed YAML files are checked.
+
+Reported errors are duplicate IDs, URLs under several publishers, unknown
+drivers and malformed alternative IDs. Reported warnings are URLs with no
+inferable driver and organizations that look like repositories or the other
+way round. With --online, sources that don't resolve through their driver
+are errors too.
+
+The exit status is 1 if there are errors.`,
+	Example: `
+# Check a publishers file
+publishers lint publishers.yml
+
+# Check the publishers in the API, listing their sources
+publishers lint --online --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if lintFormat != "text" && lintFormat != "json" {
+			log.Fatalf("unknown format %q", lintFormat)
+		}
+
+		var pubs []common.Publisher
+
+		if len(args) == 0 {
+			var err error
+			if pubs, err = apiclient.NewClient().GetPublishers(cmd.Context()); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		for _, yamlFile := range args {
+			filePublishers, err := common.LoadPublishers(yamlFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			pubs = append(pubs, filePublishers...)
+		}
+
+		opts := publishers.LintOptions{Online: lintOnline}
+		if lintOnline {
+			detector := scanner.NewForgeDetector(filepath.Join(viper.GetString("DATADIR"), "forges.json"))
+			opts.Detect = detector.Detect
+		}
+
+		issues := publishers.Lint(pubs, opts)
+
+		if lintFormat == "json" {
+			if issues == nil {
+				issues = []publishers.Issue{}
+			}
+
+			if err := json.NewEncoder(os.Stdout).Encode(issues); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			for _, issue := range issues {
+				//nolint:forbidigo
+				fmt.Println(issue.String())
+			}
+		}
+
+		if publishers.HasErrors(issues) {
+			os.Exit(1)
+		}
+	},
+}
+
+var publishersPushCmd = &cobra.Command{
+	Use:   "push publishers.yml [directory/*.yml ...]",
+	Short: "Sync publishers files into the API.",
+	Long: `Sync publishers files into the API.
+
+Publishers missing from the API are created, the changed ones are updated.
+Publishers are matched on their ID or alternativeId, and the ID is used as
+alternativeId for the publishers without one. With --deactivate-missing,
+the active API publishers missing from the files are deactivated.`,
+	Example: `
+# Preview the changes
+publishers push --dry-run publishers.yml
+
+# Make the API match publishers.yml exactly
+publishers push --deactivate-missing publishers.yml`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var pubs []common.Publisher
+
+		for _, yamlFile := range args {
+			filePublishers, err := common.LoadPublishers(yamlFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			pubs = append(pubs, filePublishers...)
+		}
+
+		if issues := publishers.Lint(pubs, publishers.LintOptions{}); publishers.HasErrors(issues) {
+			for _, issue := range issues {
+				log.Error(issue.String())
+			}
+
+			log.Fatal("fix the errors in the publishers files before pushing them")
+		}
+
+		changes, err := publishers.Push(
+			cmd.Context(), apiclient.NewClient(), pubs, publishers.PushOptions{DryRun: dryRun, Deactivate: deactivateMissing},
+		)
+
+		counts := map[publishers.PushAction]int{}
+
+		for _, change := range changes {
+			counts[change.Action]++
+
+			//nolint:forbidigo
+			fmt.Println(change.String())
+		}
+
+		log.Infof(
+			"%d created, %d updated, %d reactivated, %d deactivated, %d unchanged",
+			counts[publishers.PushCreate], counts[publishers.PushUpdate], counts[publishers.PushReactivate],
+			counts[publishers.PushDeactivate], len(pubs)-counts[publishers.PushCreate]-
+				counts[publishers.PushUpdate]-counts[publishers.PushReactivate],
+		)
+
+		if dryRun {
+			log.Info("dry run, no changes made")
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/spool"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	spoolReplayCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "only print the writes, without making them")
+
+	spoolCmd.AddCommand(spoolReplayCmd)
+
+	rootCmd.AddCommand(spoolCmd)
+}
+
+var spoolCmd = &cobra.Command{
+	Use:   "spool",
+	Short: "Manage the writes to the API left to do by crawls.",
+	Long: `Manage the writes to the API left to do by crawls.
+
+When the API is unavailable during a crawl, the software and log writes
+that failed are saved to spool.ndjson in DATADIR. Crawls replay them when
+they start.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := cmd.Help(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var spoolReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Apply the writes in the spool to the API.",
+	Long: `Apply the writes in the spool to the API, in order, dropping the ones
+superseded by a later write of the same software.
+
+The replay stops at the first write failing because the API is still
+unavailable, which stays in the spool with the following ones. Writes the
+API refuses are dropped.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		sp := spool.New(filepath.Join(viper.GetString("DATADIR"), spool.FileName))
+
+		if dryRun {
+			entries, err := sp.Entries()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			compacted := spool.Compact(entries)
+			for _, entry := range compacted {
+				fmt.Printf("%s %s %s\n", entry.SpooledAt.Format(time.RFC3339), entry.Method, entry.Endpoint)
+			}
+
+			log.Infof("%d writes to replay, %d superseded", len(compacted), len(entries)-len(compacted))
+
+			return
+		}
+
+		client, err := apiclient.New()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result, err := spool.Replay(cmd.Context(), client, sp)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Infof(
+			"%d writes applied, %d superseded, %d refused by the API, %d left for later",
+			result.Applied, result.Superseded, result.Refused, result.Remaining,
+		)
+
+		if result.Remaining > 0 {
+			log.Fatal("the API is still unavailable")
+		}
+	},
+}
@@ -5,12 +5,15 @@ import "net/url"
 // CatalogSource is one of a catalog's enumeration points. By definition a
 // source produces a list of repositories, so there is no Group flag.
 // Driver may be a code-host driver ("github", "gitlab", "bitbucket",
-// "gitea"), an enumeration driver ("json"), or an upstream API
-// ("software-catalog-api").
+// "gitea", "sourcehut", "azuredevops"), an enumeration driver ("json",
+// "csv", "yaml", "text", "gitrepo"), or an upstream API ("software-catalog-api").
+// Drivers are looked up by name in the registry package. Filter selects
+// the repositories listed by the source.
 type CatalogSource struct {
 	URL    url.URL
 	Driver string
 	Args   []string
+	Filter RepoFilter
 }
 
 type Catalog struct {
@@ -0,0 +1,274 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepoInfo is what a Lister knows about a repository before emitting it,
+// to match it against a RepoFilter. Zero values mean unknown: rules on
+// unknown properties don't match.
+type RepoInfo struct {
+	// Name is the full name, eg. "org/repo".
+	Name string
+	// Topics are the repository's topics or tags.
+	Topics []string
+	// Visibility is "public", "private" or "internal".
+	Visibility string
+	Archived   bool
+	// PushedAt is the time of the last push or, where the platform doesn't
+	// have it, of the last activity.
+	PushedAt time.Time
+}
+
+// RepoFilter selects the repositories a Lister emits. A repository is
+// emitted if it matches at least one Include rule, when there are any, and
+// no Exclude rule.
+type RepoFilter struct {
+	Include []RepoRule
+	Exclude []RepoRule
+}
+
+// RepoRule is a single condition on a repository, in one of these forms:
+//
+//	name:GLOB             the full name (org/repo) or the name matches GLOB
+//	name:/REGEXP/         the full name matches REGEXP
+//	topic:TOPIC           the repository has TOPIC
+//	visibility:VISIBILITY the visibility is VISIBILITY (public, private, internal)
+//	archived              the repository is archived
+//	pushed-older-than:AGE the last push is older than AGE (eg. 365d, 8w, 72h)
+type RepoRule struct {
+	raw string
+
+	kind  string
+	value string
+	re    *regexp.Regexp
+	age   time.Duration
+}
+
+const (
+	ruleName            = "name"
+	ruleTopic           = "topic"
+	ruleVisibility      = "visibility"
+	ruleArchived        = "archived"
+	rulePushedOlderThan = "pushed-older-than"
+)
+
+// ParseRepoRule parses a rule in one of the forms documented in RepoRule.
+func ParseRepoRule(s string) (RepoRule, error) {
+	rule := RepoRule{raw: s}
+
+	kind, value, _ := strings.Cut(s, ":")
+	rule.kind = strings.ToLower(strings.TrimSpace(kind))
+	value = strings.TrimSpace(value)
+
+	switch rule.kind {
+	case ruleName:
+		if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+			re, err := regexp.Compile(value[1 : len(value)-1])
+			if err != nil {
+				return rule, fmt.Errorf("invalid rule %q: %w", s, err)
+			}
+
+			rule.re = re
+
+			break
+		}
+
+		if _, err := path.Match(value, ""); err != nil || value == "" {
+			return rule, fmt.Errorf("invalid rule %q: bad pattern", s)
+		}
+
+		rule.value = value
+	case ruleTopic:
+		if value == "" {
+			return rule, fmt.Errorf("invalid rule %q: missing topic", s)
+		}
+
+		rule.value = value
+	case ruleVisibility:
+		value = strings.ToLower(value)
+		if value != "public" && value != "private" && value != "internal" {
+			return rule, fmt.Errorf("invalid rule %q: visibility must be public, private or internal", s)
+		}
+
+		rule.value = value
+	case ruleArchived:
+		if value != "" {
+			return rule, fmt.Errorf("invalid rule %q: archived takes no value", s)
+		}
+	case rulePushedOlderThan:
+		age, err := parseAge(value)
+		if err != nil {
+			return rule, fmt.Errorf("invalid rule %q: %w", s, err)
+		}
+
+		rule.age = age
+	default:
+		return rule, fmt.Errorf("invalid rule %q: unknown kind %q", s, rule.kind)
+	}
+
+	return rule, nil
+}
+
+// parseAge parses an age in days (365d), weeks (8w) or as a Go duration (72h).
+func parseAge(s string) (time.Duration, error) {
+	const day = 24 * time.Hour
+
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+
+	return age, nil
+}
+
+// String returns the rule as it was written.
+func (r RepoRule) String() string {
+	return r.raw
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for rules.
+func (r *RepoRule) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	rule, err := ParseRepoRule(s)
+	if err != nil {
+		return err
+	}
+
+	*r = rule
+
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for rules.
+func (r RepoRule) MarshalYAML() (any, error) {
+	return r.raw, nil
+}
+
+// Match reports whether the rule matches repo.
+func (r RepoRule) Match(repo RepoInfo) bool {
+	switch r.kind {
+	case ruleName:
+		if repo.Name == "" {
+			return false
+		}
+
+		if r.re != nil {
+			return r.re.MatchString(repo.Name)
+		}
+
+		pattern := strings.ToLower(r.value)
+		fullName := strings.ToLower(repo.Name)
+
+		if ok, _ := path.Match(pattern, fullName); ok {
+			return true
+		}
+
+		ok, _ := path.Match(pattern, path.Base(fullName))
+
+		return ok
+	case ruleTopic:
+		return slices.ContainsFunc(repo.Topics, func(topic string) bool {
+			return strings.EqualFold(topic, r.value)
+		})
+	case ruleVisibility:
+		return strings.EqualFold(repo.Visibility, r.value)
+	case ruleArchived:
+		return repo.Archived
+	case rulePushedOlderThan:
+		return !repo.PushedAt.IsZero() && time.Since(repo.PushedAt) > r.age
+	default:
+		return false
+	}
+}
+
+// IsZero reports whether the filter has no rules, and so matches everything.
+func (f RepoFilter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match reports whether repo passes the filter.
+func (f RepoFilter) Match(repo RepoInfo) bool {
+	matches := func(rule RepoRule) bool { return rule.Match(repo) }
+
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, matches) {
+		return false
+	}
+
+	return !slices.ContainsFunc(f.Exclude, matches)
+}
+
+// Merge returns a filter with the rules of both f and other.
+func (f RepoFilter) Merge(other RepoFilter) RepoFilter {
+	return RepoFilter{
+		Include: slices.Concat(f.Include, other.Include),
+		Exclude: slices.Concat(f.Exclude, other.Exclude),
+	}
+}
+
+// Args returns the filter as include=RULE and exclude=RULE arguments.
+func (f RepoFilter) Args() []string {
+	args := make([]string, 0, len(f.Include)+len(f.Exclude))
+
+	for _, rule := range f.Include {
+		args = append(args, "include="+rule.String())
+	}
+
+	for _, rule := range f.Exclude {
+		args = append(args, "exclude="+rule.String())
+	}
+
+	return args
+}
+
+// ParseRepoFilterArgs extracts the include=RULE and exclude=RULE arguments
+// from args, returning the filter they make and the other arguments.
+func ParseRepoFilterArgs(args []string) (RepoFilter, []string, error) {
+	var (
+		filter RepoFilter
+		rest   []string
+	)
+
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		if key != "include" && key != "exclude" {
+			rest = append(rest, arg)
+
+			continue
+		}
+
+		rule, err := ParseRepoRule(value)
+		if err != nil {
+			return RepoFilter{}, nil, err
+		}
+
+		if key == "include" {
+			filter.Include = append(filter.Include, rule)
+		} else {
+			filter.Exclude = append(filter.Exclude, rule)
+		}
+	}
+
+	return filter, rest, nil
+}
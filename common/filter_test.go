@@ -0,0 +1,138 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseRules(t *testing.T, rules ...string) []RepoRule {
+	t.Helper()
+
+	parsed := make([]RepoRule, 0, len(rules))
+
+	for _, rule := range rules {
+		r, err := ParseRepoRule(rule)
+		require.NoError(t, err, rule)
+
+		parsed = append(parsed, r)
+	}
+
+	return parsed
+}
+
+func TestRepoRule_Match(t *testing.T) {
+	repo := RepoInfo{
+		Name:       "acme/test-widgets",
+		Topics:     []string{"Experiment", "go"},
+		Visibility: "public",
+		Archived:   true,
+		PushedAt:   time.Now().Add(-400 * 24 * time.Hour),
+	}
+
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{"name:test-*", true},
+		{"name:acme/test-*", true},
+		{"name:TEST-*", true},
+		{"name:other/*", false},
+		{"name:/^acme/test-/", true},
+		{"name:/^test-/", false},
+		{"topic:experiment", true},
+		{"topic:rust", false},
+		{"visibility:public", true},
+		{"visibility:private", false},
+		{"archived", true},
+		{"pushed-older-than:365d", true},
+		{"pushed-older-than:60w", false},
+		{"pushed-older-than:24h", true},
+	}
+
+	for _, tc := range tests {
+		rule, err := ParseRepoRule(tc.rule)
+		require.NoError(t, err, tc.rule)
+
+		assert.Equal(t, tc.want, rule.Match(repo), tc.rule)
+	}
+}
+
+func TestRepoRule_unknownProperties(t *testing.T) {
+	for _, rule := range mustParseRules(t, "visibility:public", "pushed-older-than:1d", "topic:go", "name:*") {
+		assert.False(t, rule.Match(RepoInfo{}), rule.String())
+	}
+}
+
+func TestParseRepoRule_invalid(t *testing.T) {
+	for _, rule := range []string{
+		"", "name:", "name:[", "name:/(/", "topic:", "visibility:hidden",
+		"archived:yes", "pushed-older-than:soon", "pushed-older-than:-1d", "owner:acme",
+	} {
+		_, err := ParseRepoRule(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestRepoFilter_Match(t *testing.T) {
+	filter := RepoFilter{
+		Include: mustParseRules(t, "topic:publiccode", "name:app-*"),
+		Exclude: mustParseRules(t, "archived", "name:*-test"),
+	}
+
+	assert.True(t, filter.Match(RepoInfo{Name: "acme/app-one"}))
+	assert.True(t, filter.Match(RepoInfo{Name: "acme/widgets", Topics: []string{"publiccode"}}))
+	assert.False(t, filter.Match(RepoInfo{Name: "acme/widgets"}))
+	assert.False(t, filter.Match(RepoInfo{Name: "acme/app-one", Archived: true}))
+	assert.False(t, filter.Match(RepoInfo{Name: "acme/app-test"}))
+
+	assert.True(t, RepoFilter{}.Match(RepoInfo{}))
+	assert.True(t, RepoFilter{}.IsZero())
+}
+
+func TestParseRepoFilterArgs(t *testing.T) {
+	filter, rest, err := ParseRepoFilterArgs([]string{"topic=go", "exclude=archived", "include=name:app-*"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"topic=go"}, rest)
+	assert.Equal(t, []string{"include=name:app-*", "exclude=archived"}, filter.Args())
+
+	_, _, err = ParseRepoFilterArgs([]string{"exclude=bogus"})
+	require.Error(t, err)
+}
+
+func TestReadPublishers_filters(t *testing.T) {
+	payload := `---
+- name: acme
+  id: acme
+  orgs:
+    - https://github.com/acme
+  repos:
+    - https://github.com/other/app
+  exclude:
+    - archived
+    - name:/^test-/
+`
+
+	fake := FakeReadFiler{Str: payload}
+	fileReaderInject = fake.ReadFile
+
+	publishers, err := LoadPublishers("/dev/null")
+	require.NoError(t, err)
+	require.Len(t, publishers, 1)
+	require.Len(t, publishers[0].Sources, 2)
+
+	org := publishers[0].Sources[0]
+	assert.True(t, org.Group)
+	assert.Equal(t, []string{"exclude=archived", "exclude=name:/^test-/"}, org.Filter.Args())
+	assert.False(t, org.Filter.Match(RepoInfo{Name: "test-app"}))
+
+	assert.True(t, publishers[0].Sources[1].Filter.IsZero())
+
+	fileReaderInject = FakeReadFiler{Str: "- id: acme\n  exclude: [bogus]\n"}.ReadFile
+
+	_, err = LoadPublishers("/dev/null")
+	require.Error(t, err)
+}
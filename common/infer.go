@@ -2,18 +2,28 @@ package common
 
 import (
 	"net/url"
+	"strings"
 
 	"github.com/alranel/go-vcsurl/v2"
 )
 
 // InferVCSDriver returns the VCS driver name for a URL based on its hostname.
-// Returns an empty string if the platform is not recognized.
+// Returns an empty string if the platform is not recognized, in which case the
+// crawler probes the host (see scanner.ForgeDetector).
 //
 // The returned name matches the values that VCS sources use in
-// CatalogSource.Driver ("github", "gitlab", "bitbucket", "gitea"). Non-VCS
-// drivers like "json" are never inferred and must be set explicitly.
+// CatalogSource.Driver ("github", "gitlab", "bitbucket", "gitea",
+// "sourcehut", "azuredevops"). Non-VCS drivers like "json", and the "git" driver for plain
+// git servers, are never inferred and must be set explicitly.
 func InferVCSDriver(repoURL url.URL) string {
 	switch {
+	// Checked first, as go-vcsurl doesn't know about SourceHut and Azure
+	// DevOps and would probe the hosts over the network.
+	case strings.EqualFold(repoURL.Hostname(), "git.sr.ht"):
+		return "sourcehut"
+	case strings.EqualFold(repoURL.Hostname(), "dev.azure.com"),
+		strings.HasSuffix(strings.ToLower(repoURL.Hostname()), ".visualstudio.com"):
+		return "azuredevops"
 	case vcsurl.IsGitHub(&repoURL):
 		return "github"
 	case vcsurl.IsGitLab(&repoURL):
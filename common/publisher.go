@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -13,12 +14,15 @@ var fileReaderInject = os.ReadFile
 
 // CodeHosting is one of a publisher's hosting locations. It may be a single
 // repository or an account/group (Group=true). Driver is one of "github",
-// "gitlab", "bitbucket", "gitea" — code-host scanners only.
+// "gitlab", "bitbucket", "gitea", "sourcehut", "azuredevops" or "git" for
+// plain git servers — code-host scanners only. Filter selects the
+// repositories listed in groups.
 type CodeHosting struct {
 	URL    url.URL
 	Driver string
 	Args   []string
 	Group  bool
+	Filter RepoFilter
 }
 
 type Publisher struct {
@@ -28,12 +32,56 @@ type Publisher struct {
 	Sources       []CodeHosting
 }
 
-// publisherYAML is the on-disk representation. Driver is inferred from the URL.
+// PublishersFileVersion is the version of the publishers file format
+// written by MarshalPublishers.
+const PublishersFileVersion = 2
+
+// publisherYAML is the on-disk representation in the version 1 format, a
+// plain list of publishers. Driver is inferred from the URL.
+// The include and exclude rules apply to all the publisher's orgs.
 type publisherYAML struct {
 	ID            string            `yaml:"id"`
 	Name          string            `yaml:"name"`
 	Organizations []internalURL.URL `yaml:"orgs"`
 	Repositories  []internalURL.URL `yaml:"repos"`
+	Include       []RepoRule        `yaml:"include,omitempty"`
+	Exclude       []RepoRule        `yaml:"exclude,omitempty"`
+}
+
+// publishersFileV2 is the on-disk representation in the version 2 format:
+//
+//	version: 2
+//	publishers:
+//	  - id: ...
+//	    alternativeId: ...
+//	    name: ...
+//	    sources:
+//	      - url: https://github.com/org
+//	        group: true
+//	        driver: github
+//	        args: [...]
+//	        include: [...]
+//	        exclude: [...]
+type publishersFileV2 struct {
+	Version    int           `yaml:"version"`
+	Publishers []publisherV2 `yaml:"publishers"`
+}
+
+type publisherV2 struct {
+	ID            string     `yaml:"id"`
+	AlternativeID string     `yaml:"alternativeId,omitempty"`
+	Name          string     `yaml:"name,omitempty"`
+	Sources       []sourceV2 `yaml:"sources"`
+}
+
+// sourceV2 is a CodeHosting. Driver is inferred from the URL when empty.
+type sourceV2 struct {
+	URL     internalURL.URL `yaml:"url"`
+	Group   bool            `yaml:"group,omitempty"`
+	Driver  string          `yaml:"driver,omitempty"`
+	Args    []string        `yaml:"args,omitempty"`
+	Include []RepoRule      `yaml:"include,omitempty"`
+	Exclude []RepoRule      `yaml:"exclude,omitempty"`
 }
 
 // LoadPublishers loads the publishers YAML file and returns a slice of Publisher.
@@ -43,13 +91,70 @@ func LoadPublishers(path string) ([]Publisher, error) {
 		return nil, fmt.Errorf("error in reading `%s': %w", path, err)
 	}
 
-	var raw []publisherYAML
-
-	err = yaml.Unmarshal(data, &raw)
+	publishers, err := ParsePublishers(data)
 	if err != nil {
 		return nil, fmt.Errorf("error in parsing `%s': %w", path, err)
 	}
 
+	return publishers, nil
+}
+
+// ParsePublishers parses publishers in the publishers YAML format, either a
+// version 1 list of publishers or a version 2 document.
+func ParsePublishers(data []byte) ([]Publisher, error) {
+	version, err := PublishersVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 1:
+		return parsePublishersV1(data)
+	case 2:
+		return parsePublishersV2(data)
+	default:
+		return nil, fmt.Errorf("unsupported publishers file version %d", version)
+	}
+}
+
+// PublishersVersion returns the format version of a publishers YAML file:
+// 1 for a plain list, the version key otherwise.
+func PublishersVersion(data []byte) (int, error) {
+	var doc any
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+
+	switch doc.(type) {
+	case nil, []any:
+		return 1, nil
+	case map[any]any:
+		var header struct {
+			Version int `yaml:"version"`
+		}
+
+		if err := yaml.Unmarshal(data, &header); err != nil {
+			return 0, err
+		}
+
+		if header.Version == 0 {
+			return 0, errors.New("missing version")
+		}
+
+		return header.Version, nil
+	default:
+		return 0, errors.New("expected a list of publishers or a versioned document")
+	}
+}
+
+func parsePublishersV1(data []byte) ([]Publisher, error) {
+	var raw []publisherYAML
+
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
 	publishers := make([]Publisher, 0, len(raw))
 
 	for _, rawPub := range raw {
@@ -64,6 +169,7 @@ func LoadPublishers(path string) ([]Publisher, error) {
 				URL:    stdURL,
 				Driver: InferVCSDriver(stdURL),
 				Group:  true,
+				Filter: RepoFilter{Include: rawPub.Include, Exclude: rawPub.Exclude},
 			})
 		}
 
@@ -81,3 +187,77 @@ func LoadPublishers(path string) ([]Publisher, error) {
 
 	return publishers, nil
 }
+
+func parsePublishersV2(data []byte) ([]Publisher, error) {
+	var raw publishersFileV2
+
+	if err := yaml.UnmarshalStrict(data, &raw); err != nil {
+		return nil, err
+	}
+
+	publishers := make([]Publisher, 0, len(raw.Publishers))
+
+	for _, rawPub := range raw.Publishers {
+		pub := Publisher{
+			ID:            rawPub.ID,
+			AlternativeID: rawPub.AlternativeID,
+			Name:          rawPub.Name,
+		}
+
+		for _, source := range rawPub.Sources {
+			stdURL := (url.URL)(source.URL)
+			if stdURL.String() == "" {
+				return nil, fmt.Errorf("publisher %s: source without url", rawPub.ID)
+			}
+
+			driver := source.Driver
+			if driver == "" {
+				driver = InferVCSDriver(stdURL)
+			}
+
+			pub.Sources = append(pub.Sources, CodeHosting{
+				URL:    stdURL,
+				Driver: driver,
+				Args:   source.Args,
+				Group:  source.Group,
+				Filter: RepoFilter{Include: source.Include, Exclude: source.Exclude},
+			})
+		}
+
+		publishers = append(publishers, pub)
+	}
+
+	return publishers, nil
+}
+
+// MarshalPublishers returns publishers in the latest publishers YAML format.
+func MarshalPublishers(publishers []Publisher) ([]byte, error) {
+	file := publishersFileV2{
+		Version:    PublishersFileVersion,
+		Publishers: make([]publisherV2, 0, len(publishers)),
+	}
+
+	for _, pub := range publishers {
+		rawPub := publisherV2{
+			ID:            pub.ID,
+			AlternativeID: pub.AlternativeID,
+			Name:          pub.Name,
+			Sources:       make([]sourceV2, 0, len(pub.Sources)),
+		}
+
+		for _, source := range pub.Sources {
+			rawPub.Sources = append(rawPub.Sources, sourceV2{
+				URL:     internalURL.URL(source.URL),
+				Group:   source.Group,
+				Driver:  source.Driver,
+				Args:    source.Args,
+				Include: source.Filter.Include,
+				Exclude: source.Filter.Exclude,
+			})
+		}
+
+		file.Publishers = append(file.Publishers, rawPub)
+	}
+
+	return yaml.Marshal(file)
+}
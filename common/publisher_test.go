@@ -0,0 +1,87 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublishers_v2(t *testing.T) {
+	payload := `version: 2
+publishers:
+  - id: comune-a
+    alternativeId: c_a123
+    name: Comune di A
+    sources:
+      - url: https://github.com/comune-a
+        group: true
+        exclude:
+          - archived
+      - url: https://git.comune-a.it/app.git
+        driver: git
+      - url: https://code.comune-a.it/comune-a
+        group: true
+        driver: gitea
+        args: [topic=publiccode]
+`
+
+	publishers, err := ParsePublishers([]byte(payload))
+	require.NoError(t, err)
+	require.Len(t, publishers, 1)
+
+	pub := publishers[0]
+	assert.Equal(t, "comune-a", pub.ID)
+	assert.Equal(t, "c_a123", pub.AlternativeID)
+	assert.Equal(t, "Comune di A", pub.Name)
+	require.Len(t, pub.Sources, 3)
+
+	assert.Equal(t, "github", pub.Sources[0].Driver)
+	assert.True(t, pub.Sources[0].Group)
+	assert.Equal(t, []string{"exclude=archived"}, pub.Sources[0].Filter.Args())
+
+	assert.Equal(t, "git", pub.Sources[1].Driver)
+	assert.False(t, pub.Sources[1].Group)
+
+	assert.Equal(t, "gitea", pub.Sources[2].Driver)
+	assert.Equal(t, []string{"topic=publiccode"}, pub.Sources[2].Args)
+}
+
+func TestParsePublishers_invalid(t *testing.T) {
+	for _, payload := range []string{
+		"publishers: []\n",
+		"version: 3\npublishers: []\n",
+		"version: 2\npublishers:\n  - id: a\n    sources:\n      - driver: github\n",
+		"version: 2\npublishers:\n  - id: a\n    orgs: [https://github.com/a]\n",
+		"just a string",
+	} {
+		_, err := ParsePublishers([]byte(payload))
+		assert.Error(t, err, payload)
+	}
+}
+
+func TestMarshalPublishers_roundTrip(t *testing.T) {
+	v1 := `- id: comune-a
+  name: Comune di A
+  orgs:
+    - https://github.com/comune-a
+  repos:
+    - https://gitlab.com/comune-a/app
+  include:
+    - topic:publiccode
+`
+
+	publishers, err := ParsePublishers([]byte(v1))
+	require.NoError(t, err)
+
+	data, err := MarshalPublishers(publishers)
+	require.NoError(t, err)
+
+	version, err := PublishersVersion(data)
+	require.NoError(t, err)
+	assert.Equal(t, PublishersFileVersion, version)
+
+	upgraded, err := ParsePublishers(data)
+	require.NoError(t, err)
+	assert.Equal(t, publishers, upgraded)
+}
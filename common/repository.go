@@ -5,14 +5,28 @@ import (
 )
 
 // Repository is a single code repository. FileRawURL contains the direct url to the raw file.
+// Scanners that already read publiccode.yml (eg. from a git fetch) set FileContent instead,
+// and FileRawURL may be empty.
+//
+// With DeclaredURL, URL and CanonicalURL are not known in advance and are
+// set from the url declared in publiccode.yml, as for catalogs listing
+// publiccode.yml files rather than repositories.
+//
+// Relative paths in publiccode.yml, eg. the logo, are resolved against the
+// directory of FileRawURL. Scanners whose FileRawURL has the file path in the
+// query, as the Azure DevOps items API, set NoBaseURL: those paths can't be
+// resolved and aren't checked.
 type Repository struct {
 	Name                string
 	URL                 url.URL
 	CanonicalURL        url.URL
 	FileRawURL          string
+	FileContent         []byte
 	GitBranch           string
 	CatalogID           string
 	PublishersNamespace string
 	Publisher           Publisher
 	Headers             map[string]string
+	DeclaredURL         bool
+	NoBaseURL           bool
 }
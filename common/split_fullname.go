@@ -5,8 +5,13 @@ import (
 )
 
 // SplitFullName split a git FullName format to vendor and repo strings.
+// A FullName with no vendor (eg. a repo at the root of a plain git server)
+// returns an empty vendor.
 func SplitFullName(fullName string) (string, string) {
 	s := strings.Split(fullName, "/")
+	if len(s) == 1 {
+		return "", s[0]
+	}
 
 	return s[0], s[1]
 }
@@ -1,13 +1,16 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
@@ -17,23 +20,20 @@ import (
 	"github.com/alranel/go-vcsurl/v2"
 	httpclient "github.com/italia/httpclient-lib-go"
 	"github.com/italia/publiccode-crawler/v4/apiclient"
-	"github.com/italia/publiccode-crawler/v4/catalog"
 	"github.com/italia/publiccode-crawler/v4/common"
 	"github.com/italia/publiccode-crawler/v4/git"
+	"github.com/italia/publiccode-crawler/v4/linkcheck"
 	"github.com/italia/publiccode-crawler/v4/metrics"
+	"github.com/italia/publiccode-crawler/v4/registry"
+	"github.com/italia/publiccode-crawler/v4/rules"
 	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/italia/publiccode-crawler/v4/spool"
+	"github.com/italia/publiccode-crawler/v4/validation"
 	publiccode "github.com/italia/publiccode-parser-go/v5"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-// vcsHost holds the Scanner and Lister for a single VCS hosting platform.
-// Both fields normally point to the same underlying value.
-type vcsHost struct {
-	scanner scanner.Scanner
-	lister  catalog.Lister
-}
-
 // Crawler is a helper class representing a crawler.
 type Crawler struct {
 	DryRun bool
@@ -45,9 +45,20 @@ type Crawler struct {
 	catalogsWg     sync.WaitGroup
 	repositoriesWg sync.WaitGroup
 
-	hosts map[string]vcsHost
-
-	apiClient apiclient.APIClient
+	detector *scanner.ForgeDetector
+
+	apiClient apiclient.Client
+	index     *softwareIndex
+	// spool keeps the writes that failed because the API was unavailable.
+	spool *spool.Spool
+	// logSink receives the repository logs, the API if nil.
+	logSink LogSink
+	// rules are the custom rules of catalogs and publishers, from RULES_FILE.
+	rules *rules.Rules
+	// languages counts the description languages for the summary.
+	languages *languageCounter
+	// links checks the links in publiccode.yml, nil unless LINK_CHECK is set.
+	links *linkcheck.Checker
 }
 
 // NewCrawler initializes a new Crawler object and connects to Elasticsearch (if dryRun == false).
@@ -83,31 +94,52 @@ func NewCrawler(dryRun bool) *Crawler {
 		"repository_upsert_failures", "Number of failures in creating or updating software in the API",
 		crwlr.Index,
 	)
+	metrics.RegisterPrometheusCounter(
+		"broken_links", "Number of unreachable links in the processed publiccode.yml files", crwlr.Index,
+	)
 	metrics.RegisterPrometheusCounter(
 		"repository_fetch_failed", "Number of repositories where fetching publiccode.yml failed (non-404)",
 		crwlr.Index,
 	)
 
-	github := scanner.NewGitHubScanner()
-	gitlab := scanner.NewGitLabScanner()
-	bitbucket := scanner.NewBitBucketScanner()
-	gitea := scanner.NewGiteaScanner()
+	crwlr.detector = scanner.NewForgeDetector(filepath.Join(datadir, "forges.json"))
+
+	client, err := apiclient.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	crwlr.apiClient = client
+	crwlr.index = newSoftwareIndex()
+	crwlr.languages = newLanguageCounter()
+
+	if viper.GetBool("LINK_CHECK") {
+		if crwlr.links, err = linkcheck.New(linkcheck.OptionsFromConfig()); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	crwlr.hosts = map[string]vcsHost{
-		"github":    {scanner: github, lister: github},
-		"gitlab":    {scanner: gitlab, lister: gitlab},
-		"bitbucket": {scanner: bitbucket, lister: bitbucket},
-		"gitea":     {scanner: gitea, lister: gitea},
-		"forgejo":   {scanner: gitea, lister: gitea},
+	if path := viper.GetString("RULES_FILE"); path != "" {
+		if crwlr.rules, err = rules.Load(path); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	crwlr.apiClient = apiclient.NewClient()
+	if !dryRun {
+		crwlr.spool = spool.New(filepath.Join(datadir, spool.FileName))
+		crwlr.apiClient = spool.Wrap(client, crwlr.spool)
+	}
 
 	return &crwlr
 }
 
+// APIClient returns the client the crawler saves software and logs with.
+func (c *Crawler) APIClient() apiclient.Client {
+	return c.apiClient
+}
+
 // CrawlSoftwareByID crawls a single software.
-func (c *Crawler) CrawlSoftwareByID(software string, publisher common.Publisher) error {
+func (c *Crawler) CrawlSoftwareByID(ctx context.Context, software string, publisher common.Publisher) error {
 	var softwareID string
 
 	softwareURL, err := url.Parse(software)
@@ -117,7 +149,9 @@ func (c *Crawler) CrawlSoftwareByID(software string, publisher common.Publisher)
 		softwareID = path.Base(softwareURL.Path)
 	}
 
-	softwareData, err := c.apiClient.GetSoftware(softwareID)
+	c.replaySpool(ctx)
+
+	softwareData, err := c.apiClient.GetSoftware(ctx, softwareID)
 	if err != nil {
 		return err
 	}
@@ -131,28 +165,27 @@ func (c *Crawler) CrawlSoftwareByID(software string, publisher common.Publisher)
 
 	log.Infof("Processing repository: %s", softwareURL.String())
 
-	host, ok := c.hosts[common.InferVCSDriver(*repoURL)]
-	if !ok {
-		err = fmt.Errorf(
-			"publisher %s: unsupported code hosting platform for %s",
-			publisher.Name,
-			repoURL.String(),
-		)
-	} else {
-		err = host.scanner.Scan(*repoURL, publisher, c.repositories)
+	driver, err := c.resolveDriver(common.InferVCSDriver(*repoURL), *repoURL)
+	if err != nil {
+		return err
 	}
 
+	host, err := registry.NewHost(driver, registry.Config{URL: *repoURL})
 	if err != nil {
+		return fmt.Errorf("publisher %s: %s: %w", publisher.Name, repoURL.String(), err)
+	}
+
+	if err = host.Scanner.Scan(*repoURL, publisher, c.repositories); err != nil {
 		return err
 	}
 
 	close(c.repositories)
 
-	return c.crawl()
+	return c.crawl(ctx)
 }
 
 // CrawlPublishers processes a list of publishers.
-func (c *Crawler) CrawlPublishers(publishers []common.Publisher) error {
+func (c *Crawler) CrawlPublishers(ctx context.Context, publishers []common.Publisher) error {
 	sourcesNum := 0
 	for _, publisher := range publishers {
 		sourcesNum += len(publisher.Sources)
@@ -160,6 +193,9 @@ func (c *Crawler) CrawlPublishers(publishers []common.Publisher) error {
 
 	log.Infof("Scanning %d publishers (%d catalog sources)", len(publishers), sourcesNum)
 
+	c.replaySpool(ctx)
+	c.loadIndex(ctx, "")
+
 	// Process every item in publishers.
 	for _, publisher := range publishers {
 		c.publishersWg.Add(1)
@@ -172,7 +208,7 @@ func (c *Crawler) CrawlPublishers(publishers []common.Publisher) error {
 		close(c.repositories)
 	}()
 
-	return c.crawl()
+	return c.crawl(ctx)
 }
 
 // ScanPublisher scans all the publisher's catalog sources and sends discovered
@@ -194,7 +230,7 @@ func (c *Crawler) ScanPublisher(publisher common.Publisher) {
 }
 
 // CrawlCatalogs processes a list of catalogs.
-func (c *Crawler) CrawlCatalogs(catalogs []common.Catalog) error {
+func (c *Crawler) CrawlCatalogs(ctx context.Context, catalogs []common.Catalog) error {
 	sourcesNum := 0
 	for _, cat := range catalogs {
 		sourcesNum += len(cat.Sources)
@@ -202,6 +238,12 @@ func (c *Crawler) CrawlCatalogs(catalogs []common.Catalog) error {
 
 	log.Infof("Scanning %d catalogs (%d sources)", len(catalogs), sourcesNum)
 
+	c.replaySpool(ctx)
+
+	for _, cat := range catalogs {
+		c.loadIndex(ctx, cat.ID)
+	}
+
 	for _, cat := range catalogs {
 		c.catalogsWg.Add(1)
 
@@ -213,7 +255,7 @@ func (c *Crawler) CrawlCatalogs(catalogs []common.Catalog) error {
 		close(c.repositories)
 	}()
 
-	return c.crawl()
+	return c.crawl(ctx)
 }
 
 // ScanCatalog scans all sources in a catalog and sends discovered repositories
@@ -258,18 +300,21 @@ func (c *Crawler) ScanCatalog(cat common.Catalog) {
 
 // ProcessRepositories process the repositories channel, check the repo's publiccode.yml
 // and send new data to the API if the publiccode.yml file is valid.
-func (c *Crawler) ProcessRepositories(repos chan common.Repository) {
+func (c *Crawler) ProcessRepositories(ctx context.Context, repos chan common.Repository) {
 	defer c.repositoriesWg.Done()
 
 	for repository := range repos {
-		c.ProcessRepo(repository)
+		c.ProcessRepo(ctx, repository)
 	}
 }
 
 // ProcessRepo looks for a publiccode.yml file in a repository, and if found it processes it.
-func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,gocyclo,maintidx
+func (c *Crawler) ProcessRepo(ctx context.Context, repository common.Repository) { //nolint:funlen,gocyclo,maintidx
 	var logEntries []string
 
+	// The errors and warnings on publiccode.yml, sent with the logs.
+	var results []validation.Entry
+
 	var software *apiclient.Software
 	var err error
 
@@ -279,22 +324,17 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 		}
 
 		if !c.DryRun {
-			entries := strings.Join(logEntries, "\n")
-
-			var err error
-
-			switch {
-			case repository.CatalogID != "" && software != nil:
-				err = c.apiClient.PostCatalogSoftwareLog(repository.CatalogID, software.ID, entries)
-			case repository.CatalogID != "":
-				err = c.apiClient.PostCatalogLog(repository.CatalogID, entries)
-			case software != nil:
-				err = c.apiClient.PostSoftwareLog(software.ID, entries)
-			default:
-				err = c.apiClient.PostLog(entries)
+			entry := RepositoryLog{
+				Repository: repository.Name,
+				CatalogID:  repository.CatalogID,
+				Text:       strings.Join(logEntries, "\n"),
+				Validation: results,
+			}
+			if software != nil {
+				entry.SoftwareID = software.ID
 			}
 
-			if err != nil {
+			if err := c.logs().Log(ctx, entry); err != nil {
 				log.Errorf("[%s]: %s", repository.Name, err.Error())
 			}
 		}
@@ -303,12 +343,15 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 	// Increment counter for the number of repositories processed.
 	metrics.GetCounter("repository_processed", c.Index).Inc()
 
-	if repository.CatalogID != "" {
-		software, err = c.apiClient.GetCatalogSoftwareByURL(repository.CatalogID, repository.URL.String())
-	} else {
-		software, err = c.apiClient.GetSoftwareByURL(repository.URL.String())
+	if repository.DeclaredURL {
+		if err = c.resolveDeclaredURL(&repository); err != nil {
+			logEntries = append(logEntries, fmt.Sprintf("[%s] %s", repository.Name, err.Error()))
+
+			return
+		}
 	}
 
+	software, err = c.findSoftware(ctx, repository.CatalogID, repository.URL.String())
 	if err != nil {
 		logEntries = append(
 			logEntries,
@@ -336,18 +379,16 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 		return
 	}
 
-	resp, err := httpclient.GetURL(repository.FileRawURL, repository.Headers)
-	if resp.Status.Code != http.StatusOK || err != nil {
-		if resp.Status.Code == http.StatusNotFound {
-			logEntries = append(logEntries, fmt.Sprintf("[%s] publiccode.yml not found (404)", repository.Name))
-		} else {
-			// Code -1 means all backoff retries were exhausted (usually sustained rate limiting).
-			logEntries = append(logEntries, fmt.Sprintf(
-				"[%s] failed to fetch publiccode.yml (HTTP %d): %v",
-				repository.Name, resp.Status.Code, err,
-			))
-			metrics.GetCounter("repository_fetch_failed", c.Index).Inc()
-		}
+	// Where publiccode.yml was read from, for logs and for the checks on the
+	// declared url.
+	fileLocation := repository.FileRawURL
+	if fileLocation == "" {
+		fileLocation = repository.CanonicalURL.String()
+	}
+
+	content, err := c.fetchPubliccode(repository)
+	if err != nil {
+		logEntries = append(logEntries, fmt.Sprintf("[%s] %s", repository.Name, err.Error()))
 
 		return
 	}
@@ -357,21 +398,13 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 		fmt.Sprintf(
 			"[%s] publiccode.yml found at %s\n",
 			repository.CanonicalURL.String(),
-			repository.FileRawURL,
+			fileLocation,
 		),
 	)
 
-	//nolint:godox
-	// FIXME: this is hardcoded for now, because it requires changes to publiccode-parser-go.
-	domain := publiccode.Domain{
-		Host:        "github.com",
-		UseTokenFor: []string{"github.com", "api.github.com", "raw.githubusercontent.com"},
-		BasicAuth:   []string{viper.GetString("GITHUB_TOKEN")},
-	}
-
 	var parser *publiccode.Parser
 
-	parser, err = publiccode.NewParser(publiccode.ParserConfig{Domain: domain})
+	parser, err = newParser(repository)
 	if err != nil {
 		logEntries = append(
 			logEntries,
@@ -382,33 +415,64 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 	}
 
 	var parsed publiccode.PublicCode
-	parsed, err = parser.Parse(repository.FileRawURL)
-
-	valid := true
+	parsed, err = parser.ParseStream(bytes.NewReader(content))
 
-	if err != nil {
-		var validationResults publiccode.ValidationResults
-		if errors.As(err, &validationResults) {
-			var validationError publiccode.ValidationError
-			for _, res := range validationResults {
-				if errors.As(res, &validationError) {
-					valid = false
-
-					break
-				}
-			}
-		}
-	}
+	results = validation.FromError(err)
+	valid := !validation.HasErrors(results)
 
 	publisherID := viper.GetString("MAIN_PUBLISHER_ID")
 	if valid && repository.Publisher.ID != publisherID {
 		//nolint:forcetypeassert // we'd want to panic here anyway if the library returns a non v0
 		err = validateFile(
 			repository.PublishersNamespace, repository.Publisher,
-			parsed.(publiccode.PublicCodeV0), repository.FileRawURL,
+			parsed.(publiccode.PublicCodeV0), fileLocation,
 		)
 		if err != nil {
 			valid = false
+			results = append(results, validation.FromError(err)...)
+		}
+	}
+
+	// Files known only by their URL declare the repository themselves: it
+	// must at least be on the same host.
+	if v0, ok := parsed.(publiccode.PublicCodeV0); ok && repository.DeclaredURL {
+		if mismatch := checkDeclaredLocation(v0, fileLocation); mismatch != nil {
+			results = append(results, validation.FromError(mismatch)...)
+			err = errors.Join(err, mismatch)
+		}
+	}
+
+	// The custom rules of the catalog and of the publisher, including the
+	// required description languages. Their failures join the parser's,
+	// errors making the file invalid.
+	if v0, ok := parsed.(publiccode.PublicCodeV0); ok {
+		c.languages.add(slices.Collect(maps.Keys(v0.Description)))
+
+		failures, rulesErr := rules.Check(c.rules.For(repository.CatalogID, repository.Publisher.ID), v0)
+		if rulesErr != nil {
+			logEntries = append(logEntries, fmt.Sprintf("[%s] %s", repository.Name, rulesErr.Error()))
+		}
+
+		for _, failure := range failures {
+			results = append(results, failure)
+			err = errors.Join(err, failure)
+
+			if failure.Severity == validation.SeverityError {
+				valid = false
+			}
+		}
+	}
+
+	// Broken links are only warnings, they don't make the file invalid.
+	if v0, ok := parsed.(publiccode.PublicCodeV0); ok && c.links != nil {
+		baseURL, _ := fileBaseURL(repository)
+
+		for _, broken := range c.links.Check(ctx, linkcheck.Links(v0, baseURL)) {
+			results = append(results, broken)
+			logEntries = append(
+				logEntries, fmt.Sprintf("[%s] broken link: %s: %s", repository.Name, broken.Key, broken.Message),
+			)
+			metrics.GetCounter("broken_links", c.Index).Inc()
 		}
 	}
 
@@ -446,7 +510,7 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 		}
 	}
 
-	err = c.upsertSoftware(repository.CatalogID, software, url, aliases, publiccodeYml, valid)
+	err = c.upsertSoftware(ctx, repository.CatalogID, software, url, aliases, publiccodeYml, valid)
 	if err != nil {
 		logEntries = append(logEntries, fmt.Sprintf("[%s]: %s", repository.Name, err.Error()))
 
@@ -480,11 +544,181 @@ func (c *Crawler) ProcessRepo(repository common.Repository) { //nolint:funlen,go
 	}
 }
 
+// replaySpool applies the writes left in the spool by the previous runs,
+// before crawling so that this run's writes come after them.
+func (c *Crawler) replaySpool(ctx context.Context) {
+	if c.spool == nil {
+		return
+	}
+
+	result, err := spool.Replay(ctx, c.apiClient, c.spool)
+	if err != nil {
+		log.Errorf("can't replay the spool: %s", err)
+
+		return
+	}
+
+	if result.Applied+result.Superseded+result.Refused+result.Remaining > 0 {
+		log.Infof(
+			"Spool replayed: %d writes applied, %d superseded, %d refused by the API, %d left for later",
+			result.Applied, result.Superseded, result.Refused, result.Remaining,
+		)
+	}
+}
+
+// loadIndex loads the software of catalogID, or outside of catalogs if
+// empty, from the API into the index. If that fails, ProcessRepo looks
+// the software up in the API for each repository instead.
+func (c *Crawler) loadIndex(ctx context.Context, catalogID string) {
+	if c.index == nil {
+		return
+	}
+
+	count, err := c.index.load(ctx, c.apiClient, catalogID)
+	if err != nil {
+		log.Warnf("can't load software from the API, looking it up per repository: %s", err)
+
+		return
+	}
+
+	if catalogID != "" {
+		log.Infof("Loaded %d software of catalog %s from the API", count, catalogID)
+	} else {
+		log.Infof("Loaded %d software from the API", count)
+	}
+}
+
+// findSoftware returns the software of catalogID with url as URL or alias,
+// or nil, from the index if loaded or else from the API.
+func (c *Crawler) findSoftware(ctx context.Context, catalogID, url string) (*apiclient.Software, error) {
+	if software, loaded := c.index.lookup(catalogID, url); loaded {
+		return software, nil
+	}
+
+	if catalogID != "" {
+		return c.apiClient.GetCatalogSoftwareByURL(ctx, catalogID, url)
+	}
+
+	return c.apiClient.GetSoftwareByURL(ctx, url)
+}
+
+// fetchPubliccode returns the content of the repository's publiccode.yml,
+// downloading it from FileRawURL unless the scanner already read it.
+func (c *Crawler) fetchPubliccode(repository common.Repository) ([]byte, error) {
+	if repository.FileContent != nil {
+		return repository.FileContent, nil
+	}
+
+	resp, err := httpclient.GetURL(repository.FileRawURL, repository.Headers)
+	if resp.Status.Code != http.StatusOK || err != nil {
+		if resp.Status.Code == http.StatusNotFound {
+			return nil, errors.New("publiccode.yml not found (404)")
+		}
+
+		metrics.GetCounter("repository_fetch_failed", c.Index).Inc()
+
+		// Code -1 means all backoff retries were exhausted (usually sustained rate limiting).
+		return nil, fmt.Errorf("failed to fetch publiccode.yml (HTTP %d): %v", resp.Status.Code, err) //nolint:errorlint
+	}
+
+	return resp.Body, nil
+}
+
+// resolveDeclaredURL sets the URL, CanonicalURL and Name of a repository
+// known only by its publiccode.yml from the url declared in the file.
+// The downloaded file is kept in FileContent.
+func (c *Crawler) resolveDeclaredURL(repository *common.Repository) error {
+	content, err := c.fetchPubliccode(*repository)
+	if err != nil {
+		return err
+	}
+
+	repository.FileContent = content
+
+	// The file is parsed again, with all the checks, in ProcessRepo. Here
+	// we only need its url.
+	parser, err := publiccode.NewParser(publiccode.ParserConfig{DisableExternalChecks: true})
+	if err != nil {
+		return fmt.Errorf("can't create a Parser: %w", err)
+	}
+
+	parsed, _ := parser.ParseStream(bytes.NewReader(content))
+	if parsed == nil || parsed.Url() == nil || parsed.Url().Host == "" {
+		metrics.GetCounter("repository_bad_publiccodeyml", c.Index).Inc()
+
+		return errors.New("BAD publiccode.yml: no valid url declared")
+	}
+
+	declared := url.URL(*parsed.Url())
+
+	repository.URL = declared
+	repository.CanonicalURL = declared
+	repository.Name = strings.Trim(strings.TrimSuffix(declared.Path, ".git"), "/")
+
+	return nil
+}
+
+// fileBaseURL returns the URL of the directory publiccode.yml was fetched
+// from, which relative paths in it refer to, or nil if it wasn't fetched by
+// URL or the scanner set NoBaseURL.
+func fileBaseURL(repository common.Repository) (*url.URL, error) {
+	if repository.FileRawURL == "" || repository.NoBaseURL {
+		return nil, nil //nolint:nilnil
+	}
+
+	baseURL, err := url.Parse(repository.FileRawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Dir(baseURL.Path)
+	baseURL.RawPath = ""
+	baseURL.RawQuery = ""
+
+	return baseURL, nil
+}
+
+// newParser returns a publiccode.yml parser resolving the relative paths in
+// the file (eg. logo) against the directory of the repository's FileRawURL.
+// Repositories with no raw URL, or with NoBaseURL, have nothing to resolve
+// them against, so checks on external files are disabled.
+func newParser(repository common.Repository) (*publiccode.Parser, error) {
+	//nolint:godox
+	// FIXME: this is hardcoded for now, because it requires changes to publiccode-parser-go.
+	domain := publiccode.Domain{
+		Host:        "github.com",
+		UseTokenFor: []string{"github.com", "api.github.com", "raw.githubusercontent.com"},
+		BasicAuth:   []string{viper.GetString("GITHUB_TOKEN")},
+	}
+
+	config := publiccode.ParserConfig{Domain: domain}
+
+	baseURL, err := fileBaseURL(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL == nil {
+		config.DisableExternalChecks = true
+	} else {
+		config.BaseURL = baseURL.String()
+	}
+
+	return publiccode.NewParser(config)
+}
+
 // scanCodeHosting dispatches a publisher's code hosting location. Group
 // distinguishes account/group scans from single-repo scans.
 func (c *Crawler) scanCodeHosting(
 	host common.CodeHosting, publisher common.Publisher, repos chan common.Repository,
 ) error {
+	var err error
+
+	host.Driver, err = c.resolveDriver(host.Driver, host.URL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", publisher.Name, err)
+	}
+
 	if host.Driver == "" {
 		return fmt.Errorf(
 			"%s: unrecognized platform for %s, skipping",
@@ -493,29 +727,55 @@ func (c *Crawler) scanCodeHosting(
 		)
 	}
 
-	vcs, ok := c.hosts[host.Driver]
-	if !ok {
+	vcs, err := registry.NewHost(host.Driver, registry.Config{URL: host.URL, Args: host.Args, Filter: host.Filter})
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", publisher.Name, host.URL.String(), err)
+	}
+
+	if host.Group {
+		if vcs.Lister == nil {
+			return fmt.Errorf(
+				"%s: driver %q can't list the repositories in %s",
+				publisher.Name, host.Driver, host.URL.String(),
+			)
+		}
+
+		return vcs.Lister.List(host.URL, publisher, repos)
+	}
+
+	if vcs.Scanner == nil {
 		return fmt.Errorf(
-			"%s: unknown code hosting driver %q for %s",
-			publisher.Name,
-			host.Driver,
-			host.URL.String(),
+			"%s: driver %q can't scan the single repository %s",
+			publisher.Name, host.Driver, host.URL.String(),
 		)
 	}
 
-	if host.Group {
-		return vcs.lister.List(host.URL, publisher, repos)
+	return vcs.Scanner.Scan(host.URL, publisher, repos)
+}
+
+// resolveDriver returns driver or, if it's empty, the driver for the forge
+// detected on the host of u. An empty string means the host is not supported.
+func (c *Crawler) resolveDriver(driver string, u url.URL) (string, error) {
+	if driver != "" {
+		return driver, nil
 	}
 
-	return vcs.scanner.Scan(host.URL, publisher, repos)
+	return c.detector.Detect(u)
 }
 
 // scanCatalogSource dispatches a single catalog source. A source is always
 // a list of repositories: code-host drivers (github/gitlab/...) go through
-// List, the json driver enumerates URLs and recurses one-by-one.
+// List, catalog drivers (json) enumerate URLs and recurse one-by-one.
 func (c *Crawler) scanCatalogSource(
 	src common.CatalogSource, publisher common.Publisher, repos chan common.Repository,
 ) error {
+	var err error
+
+	src.Driver, err = c.resolveDriver(src.Driver, src.URL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", publisher.Name, err)
+	}
+
 	if src.Driver == "" {
 		return fmt.Errorf(
 			"%s: unrecognized platform for %s, skipping",
@@ -524,55 +784,78 @@ func (c *Crawler) scanCatalogSource(
 		)
 	}
 
-	if src.Driver == "json" {
-		return c.scanJSONCatalog(src, publisher, repos)
+	cfg := registry.Config{URL: src.URL, Args: src.Args, Filter: src.Filter}
+
+	if registry.IsCatalog(src.Driver) {
+		return c.scanCatalog(src, cfg, publisher, repos)
+	}
+
+	vcs, err := registry.NewHost(src.Driver, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", publisher.Name, src.URL.String(), err)
 	}
 
-	vcs, ok := c.hosts[src.Driver]
-	if !ok {
+	if vcs.Lister == nil {
 		return fmt.Errorf(
-			"%s: unknown catalog driver %q for %s",
-			publisher.Name,
-			src.Driver,
-			src.URL.String(),
+			"%s: driver %q can't list the repositories in %s",
+			publisher.Name, src.Driver, src.URL.String(),
 		)
 	}
 
-	return vcs.lister.List(src.URL, publisher, repos)
+	return vcs.Lister.List(src.URL, publisher, repos)
 }
 
-// scanJSONCatalog enumerates repository URLs from a JSON catalog and dispatches
-// each one as a single-repo code hosting entry.
-func (c *Crawler) scanJSONCatalog(
-	src common.CatalogSource, publisher common.Publisher, repos chan common.Repository,
+// scanCatalog lists the repositories in a catalog or, for enumerating
+// catalogs, dispatches each entry as a code hosting location. Entries of
+// publiccode.yml catalogs go straight to ProcessRepo instead.
+func (c *Crawler) scanCatalog(
+	src common.CatalogSource, cfg registry.Config, publisher common.Publisher, repos chan common.Repository,
 ) error {
-	if len(src.Args) == 0 {
-		return fmt.Errorf(
-			"%s: json source %s is missing the JSONPath argument",
-			publisher.Name,
-			src.URL.String(),
-		)
+	cat, err := registry.NewCatalog(src.Driver, cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", publisher.Name, src.URL.String(), err)
 	}
 
-	cat := catalog.NewJSONDriver(src.Args[0])
+	if cat.Lister != nil {
+		return cat.Lister.List(src.URL, publisher, repos)
+	}
 
-	urls, err := cat.Enumerate(context.Background(), src.URL)
+	entries, err := cat.Enumerator.Enumerate(context.Background(), src.URL)
 	if err != nil {
 		return fmt.Errorf("%s: %w", publisher.Name, err)
 	}
 
-	for _, repoURL := range urls {
+	if cat.Publiccode {
+		for _, entry := range entries {
+			repos <- common.Repository{
+				Name:        entry.URL.String(),
+				FileRawURL:  entry.URL.String(),
+				Publisher:   publisher,
+				DeclaredURL: true,
+			}
+		}
+
+		return nil
+	}
+
+	for _, entry := range entries {
 		host := common.CodeHosting{
-			URL:    repoURL,
-			Driver: common.InferVCSDriver(repoURL),
-			Group:  false,
+			URL:    entry.URL,
+			Driver: entry.Driver,
+			Args:   entry.Args,
+			Group:  entry.Group,
+			Filter: src.Filter.Merge(entry.Filter),
+		}
+
+		if host.Driver == "" {
+			host.Driver = common.InferVCSDriver(entry.URL)
 		}
 
 		if err := c.scanCodeHosting(host, publisher, repos); err != nil {
 			if errors.Is(err, scanner.ErrPubliccodeNotFound) {
-				log.Warnf("[%s] %s", repoURL.String(), err.Error())
+				log.Warnf("[%s] %s", entry.URL.String(), err.Error())
 			} else {
-				log.Warnf("[%s] %s (skipping)", repoURL.String(), err.Error())
+				log.Warnf("[%s] %s (skipping)", entry.URL.String(), err.Error())
 			}
 		}
 	}
@@ -580,7 +863,7 @@ func (c *Crawler) scanJSONCatalog(
 	return nil
 }
 
-func (c *Crawler) crawl() error {
+func (c *Crawler) crawl(ctx context.Context) error {
 	reposChan := make(chan common.Repository)
 
 	// Start the metrics server.
@@ -598,7 +881,7 @@ func (c *Crawler) crawl() error {
 
 		go func(workerID int) {
 			log.Debugf("Starting ProcessRepositories() goroutine (#%d)", workerID)
-			c.ProcessRepositories(reposChan)
+			c.ProcessRepositories(ctx, reposChan)
 		}(idx)
 	}
 
@@ -609,6 +892,12 @@ func (c *Crawler) crawl() error {
 	close(reposChan)
 	c.repositoriesWg.Wait()
 
+	if c.links != nil {
+		if err := c.links.Save(); err != nil {
+			log.Warn(err)
+		}
+	}
+
 	fetchFailed := metrics.GetCounterValue("repository_fetch_failed", c.Index)
 
 	summary := fmt.Sprintf(
@@ -630,13 +919,56 @@ func (c *Crawler) crawl() error {
 		)
 	}
 
+	if brokenLinks := metrics.GetCounterValue("broken_links", c.Index); brokenLinks > 0 {
+		summary += fmt.Sprintf("\nBroken links in publiccode.yml files: %v", brokenLinks)
+	}
+
+	if languages := c.languages.summary(); languages != "" {
+		summary += "\npubliccode.yml files by description language: " + languages
+	}
+
+	if detected := c.detector.Detected(); len(detected) > 0 {
+		hosts := slices.Sorted(maps.Keys(detected))
+
+		summary += "\nAuto-detected code hosting platforms:"
+		for _, host := range hosts {
+			summary += fmt.Sprintf("\n  %s: %s (%s driver)", host, detected[host].Forge, detected[host].Driver)
+		}
+	}
+
 	log.Info(summary)
 
 	return nil
 }
 
+// checkDeclaredLocation returns a warning if publiccode.yml, found at
+// fileRawURL, isn't on the host of the url it declares. Files served by a
+// code hosting platform vcsurl recognizes are skipped: validateFile checks
+// their repository.
+func checkDeclaredLocation(parsed publiccode.PublicCodeV0, fileRawURL string) error {
+	u, err := url.Parse(fileRawURL)
+	if err != nil || vcsurl.GetRepo(u) != nil || parsed.Url() == nil {
+		return nil
+	}
+
+	declared := (*url.URL)(parsed.Url())
+	if strings.EqualFold(u.Hostname(), declared.Hostname()) {
+		return nil
+	}
+
+	return validation.Entry{
+		Key:      "url",
+		Severity: validation.SeverityWarning,
+		Message: fmt.Sprintf(
+			"declared url (%s) is not on the host publiccode.yml was found at (%s)",
+			declared, fileRawURL,
+		),
+		RuleID: validation.RuleURLHost,
+	}
+}
+
 // validateFile performs additional validations that are not strictly mandated
-// by the publiccode.yml Standard.
+// by the publiccode.yml Standard. Failures are returned as validation.Entry.
 func validateFile(
 	publishersNamespace string, publisher common.Publisher,
 	parsed publiccode.PublicCodeV0, fileRawURL string,
@@ -654,11 +986,16 @@ func validateFile(
 		repo1.Scheme, repo2.Scheme = "", ""
 
 		if !strings.EqualFold(repo1.String(), repo2.String()) {
-			return fmt.Errorf(
-				"declared url (%s) and actual publiccode.yml location URL (%s) "+
-					"are not in the same repo: '%s' vs '%s'",
-				parsed.Url(), fileRawURL, repo2, repo1,
-			)
+			return validation.Entry{
+				Key:      "url",
+				Severity: validation.SeverityError,
+				Message: fmt.Sprintf(
+					"declared url (%s) and actual publiccode.yml location URL (%s) "+
+						"are not in the same repo: '%s' vs '%s'",
+					parsed.Url(), fileRawURL, repo2, repo1,
+				),
+				RuleID: validation.RuleURLRepository,
+			}
 		}
 	}
 
@@ -677,13 +1014,18 @@ func validateFile(
 	expected := publishersNamespace + publisher.AlternativeID
 
 	if !strings.EqualFold(strings.TrimSpace(expected), strings.TrimSpace(organisationURI)) {
-		return fmt.Errorf(
-			"organisation is '%s', but '%s' was expected for '%s' in %s",
-			organisationURI,
-			expected,
-			publisher.Name,
-			fileRawURL,
-		)
+		return validation.Entry{
+			Key:      "organisation.uri",
+			Severity: validation.SeverityError,
+			Message: fmt.Sprintf(
+				"organisation is '%s', but '%s' was expected for '%s' in %s",
+				organisationURI,
+				expected,
+				publisher.Name,
+				fileRawURL,
+			),
+			RuleID: validation.RuleOrganisationURI,
+		}
 	}
 
 	return nil
@@ -691,6 +1033,7 @@ func validateFile(
 
 // upsertSoftware posts or patches a software entry depending on whether it already exists.
 func (c *Crawler) upsertSoftware(
+	ctx context.Context,
 	catalogID string,
 	software *apiclient.Software,
 	repoURL string,
@@ -710,15 +1053,26 @@ func (c *Crawler) upsertSoftware(
 		// false so that we know about the new software and for example
 		// [publiccode-issueopener](https://github.com/italia/publiccode-issueopener) can
 		// notify maintainers about the errors.
+		var (
+			created *apiclient.Software
+			err     error
+		)
+
 		if catalogID != "" {
-			_, err := c.apiClient.PostCatalogSoftware(catalogID, repoURL, aliases, string(publiccodeYml), valid)
+			created, err = c.apiClient.PostCatalogSoftware(
+				ctx, catalogID, repoURL, aliases, string(publiccodeYml), valid,
+			)
+		} else {
+			created, err = c.apiClient.PostSoftware(ctx, repoURL, aliases, string(publiccodeYml), valid)
+		}
 
+		if err != nil {
 			return err
 		}
 
-		_, err := c.apiClient.PostSoftware(repoURL, aliases, string(publiccodeYml), valid)
+		c.index.put(catalogID, nil, *created)
 
-		return err
+		return nil
 	}
 
 	// Known software: merge any aliases from the API that we don't already have.
@@ -726,14 +1080,26 @@ func (c *Crawler) upsertSoftware(
 
 	metrics.GetCounter("repository_known", c.Index).Inc()
 
-	if !c.DryRun {
-		if catalogID != "" {
-			return c.apiClient.PatchCatalogSoftware(catalogID, software.ID, repoURL, aliases, string(publiccodeYml))
-		}
+	if c.DryRun {
+		return nil
+	}
+
+	var err error
+
+	if catalogID != "" {
+		err = c.apiClient.PatchCatalogSoftware(
+			ctx, catalogID, software.ID, repoURL, aliases, string(publiccodeYml),
+		)
+	} else {
+		err = c.apiClient.PatchSoftware(ctx, software.ID, repoURL, aliases, string(publiccodeYml))
+	}
 
-		return c.apiClient.PatchSoftware(software.ID, repoURL, aliases, string(publiccodeYml))
+	if err != nil {
+		return err
 	}
 
+	c.index.put(catalogID, software, patched(*software, repoURL, aliases))
+
 	return nil
 }
 
@@ -1,12 +1,28 @@
 package crawler
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/catalog"
 	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/linkcheck"
+	"github.com/italia/publiccode-crawler/v4/registry"
+	"github.com/italia/publiccode-crawler/v4/rules"
+	"github.com/italia/publiccode-crawler/v4/validation"
 	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMergeNewAliases_addsNew(t *testing.T) {
@@ -141,3 +157,473 @@ func TestValidateFile_OrganisationMismatch(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestResolveDeclaredURL(t *testing.T) {
+	c := &Crawler{}
+
+	repo := common.Repository{
+		Name:        "https://www.example.org/software/publiccode.yml",
+		FileRawURL:  "https://www.example.org/software/publiccode.yml",
+		FileContent: []byte("publiccodeYmlVersion: '0.4'\nurl: https://github.com/acme/app.git\n"),
+		DeclaredURL: true,
+	}
+
+	require.NoError(t, c.resolveDeclaredURL(&repo))
+
+	assert.Equal(t, "https://github.com/acme/app.git", repo.URL.String())
+	assert.Equal(t, repo.URL, repo.CanonicalURL)
+	assert.Equal(t, "acme/app", repo.Name)
+}
+
+// A catalog entry pointing to a publiccode.yml in a repository other than
+// the one it declares.
+func TestValidateFile_declaredForeignRepo(t *testing.T) {
+	pc := newPublicCode(t, "https://github.com/acme/app", "")
+
+	err := validateFile("", common.Publisher{ID: "cat"}, pc,
+		"https://raw.githubusercontent.com/someone-else/app/main/publiccode.yml")
+
+	var entry validation.Entry
+	require.ErrorAs(t, err, &entry)
+	assert.Equal(t, validation.RuleURLRepository, entry.RuleID)
+	assert.Equal(t, validation.SeverityError, entry.Severity)
+}
+
+func TestCheckDeclaredLocation(t *testing.T) {
+	pc := newPublicCode(t, "https://github.com/acme/app", "")
+
+	assert.NoError(t, checkDeclaredLocation(pc, "https://github.com/acme/app"))
+	// Files on a code hosting platform are up to validateFile.
+	assert.NoError(t, checkDeclaredLocation(pc, "https://raw.githubusercontent.com/other/app/main/publiccode.yml"))
+
+	err := checkDeclaredLocation(pc, "https://www.example.org/software/publiccode.yml")
+
+	var entry validation.Entry
+	require.ErrorAs(t, err, &entry)
+	assert.Equal(t, validation.RuleURLHost, entry.RuleID)
+	assert.Equal(t, validation.SeverityWarning, entry.Severity)
+}
+
+func TestResolveDeclaredURL_noURL(t *testing.T) {
+	c := &Crawler{}
+
+	repo := common.Repository{
+		FileContent: []byte("publiccodeYmlVersion: '0.4'\nname: app\n"),
+		DeclaredURL: true,
+	}
+
+	require.Error(t, c.resolveDeclaredURL(&repo))
+}
+
+func TestScanCatalogSource_publiccodeEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "files.txt")
+	require.NoError(t, os.WriteFile(path, []byte("https://www.example.org/app/publiccode.yml\n"), 0o600))
+
+	src := common.CatalogSource{
+		URL:    url.URL{Scheme: "file", Path: path},
+		Driver: "text",
+		Args:   []string{"entries=publiccode"},
+	}
+	publisher := common.Publisher{ID: "cat", Name: "Catalog"}
+
+	repos := make(chan common.Repository, 1)
+	require.NoError(t, (&Crawler{}).scanCatalogSource(src, publisher, repos))
+	close(repos)
+
+	repo := <-repos
+	assert.Equal(t, "https://www.example.org/app/publiccode.yml", repo.FileRawURL)
+	assert.True(t, repo.DeclaredURL)
+	assert.Equal(t, publisher, repo.Publisher)
+	assert.Empty(t, repo.URL.Host)
+}
+
+// stubEnumerator is a catalog listing fixed entries.
+type stubEnumerator []catalog.Entry
+
+func (e stubEnumerator) Enumerate(context.Context, url.URL) ([]catalog.Entry, error) {
+	return e, nil
+}
+
+// listerFunc is a catalog.Lister calling a function.
+type listerFunc func(url.URL, common.Publisher, chan common.Repository) error
+
+func (f listerFunc) List(u url.URL, publisher common.Publisher, repos chan common.Repository) error {
+	return f(u, publisher, repos)
+}
+
+func TestScanCatalogSource_entryFilterAndArgs(t *testing.T) {
+	exclude, err := common.ParseRepoRule("name:legacy-*")
+	require.NoError(t, err)
+
+	archived, err := common.ParseRepoRule("archived")
+	require.NoError(t, err)
+
+	var got registry.Config
+
+	registry.RegisterCatalog("test-entry-filter", func(registry.Config) (registry.Catalog, error) {
+		return registry.Catalog{Enumerator: stubEnumerator{{
+			URL:    url.URL{Scheme: "https", Host: "git.example.org", Path: "/org"},
+			Group:  true,
+			Driver: "test-entry-filter-host",
+			Args:   []string{"token=secret"},
+			Filter: common.RepoFilter{Exclude: []common.RepoRule{exclude}},
+		}}}, nil
+	})
+	registry.RegisterHost("test-entry-filter-host", func(cfg registry.Config) (registry.Host, error) {
+		got = cfg
+
+		return registry.Host{Lister: listerFunc(func(url.URL, common.Publisher, chan common.Repository) error {
+			return nil
+		})}, nil
+	})
+
+	src := common.CatalogSource{
+		URL:    url.URL{Scheme: "https", Host: "catalog.example.org"},
+		Driver: "test-entry-filter",
+		Filter: common.RepoFilter{Exclude: []common.RepoRule{archived}},
+	}
+
+	require.NoError(t, (&Crawler{}).scanCatalogSource(src, common.Publisher{ID: "cat"}, nil))
+
+	assert.Equal(t, []string{"token=secret"}, got.Args)
+	assert.Equal(t, []string{"exclude=archived", "exclude=name:legacy-*"}, got.Filter.Args())
+}
+
+func newMemoryCrawler(t *testing.T) (*Crawler, *apiclient.MemoryClient) {
+	t.Helper()
+
+	viper.Set("SKIP_VITALITY", true)
+	t.Cleanup(func() { viper.Set("SKIP_VITALITY", false) })
+
+	client := apiclient.NewMemoryClient()
+
+	return &Crawler{apiClient: client}, client
+}
+
+func memoryRepo(t *testing.T, repoURL string) common.Repository {
+	t.Helper()
+
+	u, err := url.Parse(repoURL)
+	require.NoError(t, err)
+
+	return common.Repository{
+		Name:         strings.TrimPrefix(u.Path, "/"),
+		URL:          *u,
+		CanonicalURL: *u,
+		FileContent:  []byte("publiccodeYmlVersion: '0.4'\nurl: " + repoURL + "\nname: app\n"),
+		Publisher:    common.Publisher{ID: "pub", Name: "Publisher"},
+	}
+}
+
+func TestProcessRepo_createsSoftware(t *testing.T) {
+	c, client := newMemoryCrawler(t)
+
+	c.ProcessRepo(t.Context(), memoryRepo(t, "https://github.com/acme/app"))
+
+	software := client.Software("")
+	require.Len(t, software, 1)
+	assert.Equal(t, "https://github.com/acme/app", software[0].URL)
+	assert.Contains(t, software[0].PubliccodeYml, "name: app")
+	assert.False(t, software[0].Active, "an invalid publiccode.yml creates inactive software")
+
+	logs := client.Logs()
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0].Message, "BAD publiccode.yml")
+}
+
+func TestProcessRepo_updatesKnownSoftware(t *testing.T) {
+	c, client := newMemoryCrawler(t)
+
+	created, err := client.PostSoftware(t.Context(),
+		"https://github.com/acme/app", []string{"https://github.com/acme/old"}, "", true,
+	)
+	require.NoError(t, err)
+
+	repo := memoryRepo(t, "https://github.com/acme/app")
+	renamed, err := url.Parse("https://github.com/acme/renamed")
+	require.NoError(t, err)
+	repo.CanonicalURL = *renamed
+
+	c.ProcessRepo(t.Context(), repo)
+
+	software := client.Software("")
+	require.Len(t, software, 1)
+	assert.Equal(t, created.ID, software[0].ID)
+	assert.Equal(t, "https://github.com/acme/renamed", software[0].URL)
+	assert.ElementsMatch(t, []string{"https://github.com/acme/old", "https://github.com/acme/app"}, software[0].Aliases)
+	assert.Contains(t, software[0].PubliccodeYml, "name: app")
+
+	logs := client.Logs()
+	require.Len(t, logs, 1)
+	assert.Equal(t, created.ID, logs[0].SoftwareID)
+}
+
+func TestProcessRepo_catalogSoftware(t *testing.T) {
+	c, client := newMemoryCrawler(t)
+
+	repo := memoryRepo(t, "https://github.com/acme/app")
+	repo.CatalogID = "cat"
+
+	c.ProcessRepo(t.Context(), repo)
+
+	assert.Empty(t, client.Software(""))
+	require.Len(t, client.Software("cat"), 1)
+
+	logs := client.Logs()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "cat", logs[0].CatalogID)
+}
+
+// recordingSink keeps the repository logs it receives.
+type recordingSink struct {
+	logs []RepositoryLog
+}
+
+func (s *recordingSink) Log(_ context.Context, entry RepositoryLog) error {
+	s.logs = append(s.logs, entry)
+
+	return nil
+}
+
+func TestProcessRepo_validationEntries(t *testing.T) {
+	c, client := newMemoryCrawler(t)
+
+	repo := memoryRepo(t, "https://github.com/acme/app")
+	c.ProcessRepo(t.Context(), repo)
+
+	logs := client.Logs()
+	require.Len(t, logs, 1)
+
+	text, entries, err := validation.ParseMessage(logs[0].Message)
+	require.NoError(t, err)
+	assert.Contains(t, text, "BAD publiccode.yml")
+	require.NotEmpty(t, entries)
+	assert.True(t, validation.HasErrors(entries))
+	assert.Contains(t, entries, validation.Entry{
+		Key: "softwareType", Line: 1, Column: 1, Severity: validation.SeverityError,
+		Message: "softwareType is a required field", RuleID: validation.RuleParser,
+	})
+
+	for _, entry := range entries {
+		assert.Equal(t, validation.RuleParser, entry.RuleID)
+		assert.NotEmpty(t, entry.Key)
+	}
+
+	sink := &recordingSink{}
+	c.SetLogSink(sink)
+
+	c.ProcessRepo(t.Context(), repo)
+
+	assert.Len(t, client.Logs(), 1, "logs go to the sink instead of the API")
+	require.Len(t, sink.logs, 1)
+	assert.Equal(t, "acme/app", sink.logs[0].Repository)
+	assert.Equal(t, client.Software("")[0].ID, sink.logs[0].SoftwareID)
+	assert.Equal(t, entries, sink.logs[0].Validation)
+}
+
+func TestProcessRepo_customRules(t *testing.T) {
+	c, _ := newMemoryCrawler(t)
+
+	sink := &recordingSink{}
+	c.SetLogSink(sink)
+
+	var err error
+	c.rules, err = rules.Parse([]byte(`
+rulesets:
+  strict:
+    - id: long-name
+      key: name
+      minLength: 10
+catalogs:
+  cat: [strict]
+`))
+	require.NoError(t, err)
+
+	c.ProcessRepo(t.Context(), memoryRepo(t, "https://github.com/acme/app"))
+
+	repo := memoryRepo(t, "https://github.com/acme/app")
+	repo.CatalogID = "cat"
+	c.ProcessRepo(t.Context(), repo)
+
+	require.Len(t, sink.logs, 2)
+
+	failure := validation.Entry{
+		Key: "name", Severity: validation.SeverityError,
+		Message: "is 3 characters long, at least 10 required", RuleID: "long-name",
+	}
+	assert.NotContains(t, sink.logs[0].Validation, failure, "the rules apply to the catalog only")
+	assert.Contains(t, sink.logs[1].Validation, failure)
+	assert.Contains(t, sink.logs[1].Text, "name: is 3 characters long")
+}
+
+func TestProcessRepo_brokenLinks(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	c, _ := newMemoryCrawler(t)
+
+	sink := &recordingSink{}
+	c.SetLogSink(sink)
+
+	var err error
+	c.links, err = linkcheck.New(linkcheck.Options{Concurrency: 1, Timeout: time.Second, CacheTTL: time.Hour})
+	require.NoError(t, err)
+
+	repo := memoryRepo(t, "https://github.com/acme/app")
+	repo.FileContent = append(repo.FileContent, []byte("landingURL: "+srv.URL+"/gone\n")...)
+
+	c.ProcessRepo(t.Context(), repo)
+
+	require.Len(t, sink.logs, 1)
+	assert.Contains(t, sink.logs[0].Validation, validation.Entry{
+		Key: "landingURL", Severity: validation.SeverityWarning,
+		Message: "'" + srv.URL + "/gone' is not reachable: HTTP 404 Not Found", RuleID: linkcheck.RuleBrokenLink,
+	})
+	assert.Contains(t, sink.logs[0].Text, "broken link: landingURL")
+}
+
+func TestProcessRepo_noBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	c, _ := newMemoryCrawler(t)
+
+	sink := &recordingSink{}
+	c.SetLogSink(sink)
+
+	// An Azure DevOps items API URL: the directory of its path isn't where
+	// logo.png is.
+	repo := memoryRepo(t, srv.URL+"/acme/app/_git/app")
+	repo.FileRawURL = srv.URL + "/acme/_apis/git/repositories/b1e4/items?path=%2Fpubliccode.yml"
+	repo.FileContent = append(repo.FileContent, []byte("logo: logo.png\n")...)
+
+	c.ProcessRepo(t.Context(), repo)
+
+	repo.NoBaseURL = true
+	c.ProcessRepo(t.Context(), repo)
+
+	require.Len(t, sink.logs, 2)
+	assert.True(t, hasEntry(sink.logs[0].Validation, "logo"), "logo.png is looked up in the wrong place")
+	assert.False(t, hasEntry(sink.logs[1].Validation, "logo"), "logo.png isn't checked")
+}
+
+func hasEntry(entries []validation.Entry, key string) bool {
+	return slices.ContainsFunc(entries, func(entry validation.Entry) bool { return entry.Key == key })
+}
+
+func TestLanguageCounter(t *testing.T) {
+	lc := newLanguageCounter()
+	assert.Empty(t, lc.summary())
+
+	lc.add([]string{"it", "en"})
+	lc.add([]string{"it", "de"})
+	lc.add([]string{"it"})
+
+	assert.Equal(t, "it: 3, de: 1, en: 1", lc.summary())
+
+	var none *languageCounter
+	none.add([]string{"it"})
+	assert.Empty(t, none.summary())
+}
+
+func TestValidateFile_ruleIDs(t *testing.T) {
+	publisher := common.Publisher{ID: "pcm", AlternativeID: "pcm", Name: "PCM"}
+
+	err := validateFile("urn:x-italian-pa:", publisher,
+		newPublicCode(t, "https://github.com/foo/bar", "urn:x-italian-pa:wrong"),
+		"https://raw.githubusercontent.com/foo/bar/main/publiccode.yml")
+	assert.Equal(t, []validation.Entry{{
+		Key:      "organisation.uri",
+		Severity: validation.SeverityError,
+		Message: "organisation is 'urn:x-italian-pa:wrong', but 'urn:x-italian-pa:pcm' was expected " +
+			"for 'PCM' in https://raw.githubusercontent.com/foo/bar/main/publiccode.yml",
+		RuleID: validation.RuleOrganisationURI,
+	}}, validation.FromError(err))
+
+	err = validateFile("urn:x-italian-pa:", publisher,
+		newPublicCode(t, "https://github.com/foo/other", "urn:x-italian-pa:pcm"),
+		"https://raw.githubusercontent.com/foo/bar/main/publiccode.yml")
+
+	entries := validation.FromError(err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, validation.RuleURLRepository, entries[0].RuleID)
+	assert.Equal(t, "url", entries[0].Key)
+}
+
+// lookupCountingClient counts the software lookups by URL that reach the API.
+type lookupCountingClient struct {
+	*apiclient.MemoryClient
+
+	lookups int
+}
+
+func (clt *lookupCountingClient) GetSoftwareByURL(
+	ctx context.Context, url string,
+) (*apiclient.Software, error) {
+	clt.lookups++
+
+	return clt.MemoryClient.GetSoftwareByURL(ctx, url)
+}
+
+func TestSoftwareIndex(t *testing.T) {
+	client := apiclient.NewMemoryClient()
+
+	created, err := client.PostSoftware(t.Context(), "https://github.com/acme/app", []string{"https://github.com/acme/old"}, "yml", false)
+	require.NoError(t, err)
+
+	idx := newSoftwareIndex()
+
+	_, loaded := idx.lookup("", "https://github.com/acme/app")
+	assert.False(t, loaded)
+
+	count, err := idx.load(t.Context(), client, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for _, u := range []string{"https://github.com/acme/app", "https://github.com/acme/old"} {
+		software, loaded := idx.lookup("", u)
+		assert.True(t, loaded)
+		require.NotNil(t, software, u)
+		assert.Equal(t, created.ID, software.ID)
+		assert.False(t, software.Active)
+		assert.Equal(t, created.CreatedAt, software.CreatedAt)
+	}
+
+	software, loaded := idx.lookup("", "https://github.com/acme/other")
+	assert.True(t, loaded)
+	assert.Nil(t, software)
+
+	previous, _ := idx.lookup("", "https://github.com/acme/app")
+	idx.put("", previous, patched(*previous, "https://github.com/acme/new", []string{"https://github.com/acme/app"}))
+
+	for _, u := range []string{"https://github.com/acme/new", "https://github.com/acme/app"} {
+		software, _ := idx.lookup("", u)
+		require.NotNil(t, software, u)
+		assert.Equal(t, created.ID, software.ID)
+	}
+
+	software, _ = idx.lookup("", "https://github.com/acme/old")
+	assert.Nil(t, software)
+
+	_, loaded = idx.lookup("cat", "https://github.com/acme/app")
+	assert.False(t, loaded)
+}
+
+func TestProcessRepo_usesIndex(t *testing.T) {
+	viper.Set("SKIP_VITALITY", true)
+	t.Cleanup(func() { viper.Set("SKIP_VITALITY", false) })
+
+	client := &lookupCountingClient{MemoryClient: apiclient.NewMemoryClient()}
+	c := &Crawler{apiClient: client, index: newSoftwareIndex()}
+
+	c.loadIndex(t.Context(), "")
+
+	c.ProcessRepo(t.Context(), memoryRepo(t, "https://github.com/acme/app"))
+	c.ProcessRepo(t.Context(), memoryRepo(t, "https://github.com/acme/app"))
+
+	assert.Zero(t, client.lookups)
+
+	software := client.Software("")
+	require.Len(t, software, 1, "the second run finds the software created by the first")
+	assert.NotEqual(t, software[0].CreatedAt, software[0].UpdatedAt)
+}
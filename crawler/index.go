@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+)
+
+// softwareIndex holds the software already in the API, by URL and alias,
+// so that ProcessRepo doesn't ask the API for each repository. The software
+// of each catalog, or outside of catalogs for "", is loaded once with load
+// and kept up to date with put as the crawler creates and updates it.
+//
+// It's safe for concurrent use.
+type softwareIndex struct {
+	mu sync.RWMutex
+
+	// byURL maps the catalog ID to the software by URL and alias. Catalogs
+	// that weren't loaded are missing.
+	byURL map[string]map[string]*apiclient.Software
+}
+
+func newSoftwareIndex() *softwareIndex {
+	return &softwareIndex{byURL: map[string]map[string]*apiclient.Software{}}
+}
+
+// load pages through the software of catalogID in the API, inactive
+// included, and indexes it, replacing what was indexed for it.
+func (idx *softwareIndex) load(ctx context.Context, client apiclient.Client, catalogID string) (int, error) {
+	software := map[string]*apiclient.Software{}
+	count := 0
+
+	err := client.ForEachCatalogSoftware(ctx, catalogID, true, func(s apiclient.Software) error {
+		indexSoftware(software, s)
+		count++
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.byURL[catalogID] = software
+
+	return count, nil
+}
+
+// lookup returns the software of catalogID with the given URL or alias, or
+// nil. loaded is false if catalogID wasn't loaded, so the index can't tell.
+func (idx *softwareIndex) lookup(catalogID, url string) (software *apiclient.Software, loaded bool) {
+	if idx == nil {
+		return nil, false
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	catalog, loaded := idx.byURL[catalogID]
+	if !loaded {
+		return nil, false
+	}
+
+	if s, ok := catalog[url]; ok {
+		found := *s
+		found.Aliases = slices.Clone(s.Aliases)
+
+		return &found, true
+	}
+
+	return nil, true
+}
+
+// put indexes software as created, or as updated from previous, in
+// catalogID, dropping the URL and aliases previous had. It does nothing if
+// catalogID wasn't loaded.
+func (idx *softwareIndex) put(catalogID string, previous *apiclient.Software, software apiclient.Software) {
+	if idx == nil {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	catalog, loaded := idx.byURL[catalogID]
+	if !loaded {
+		return
+	}
+
+	if previous != nil {
+		for _, url := range append([]string{previous.URL}, previous.Aliases...) {
+			if s, ok := catalog[url]; ok && s.ID == previous.ID {
+				delete(catalog, url)
+			}
+		}
+	}
+
+	indexSoftware(catalog, software)
+}
+
+// indexSoftware adds software to byURL under its URL and aliases. The
+// publiccode.yml isn't needed for lookups and is dropped to save memory.
+func indexSoftware(byURL map[string]*apiclient.Software, software apiclient.Software) {
+	software.PubliccodeYml = ""
+	software.Aliases = slices.Clone(software.Aliases)
+
+	byURL[software.URL] = &software
+	for _, alias := range software.Aliases {
+		byURL[alias] = &software
+	}
+}
+
+// patched returns software as the API has it after a PATCH with url and
+// aliases.
+func patched(software apiclient.Software, url string, aliases []string) apiclient.Software {
+	software.URL = url
+	software.Aliases = aliases
+	software.UpdatedAt = time.Now().UTC()
+
+	return software
+}
@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// languageCounter counts the publiccode.yml files offering a description
+// in each language, for the run summary. A nil *languageCounter counts
+// nothing.
+type languageCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newLanguageCounter() *languageCounter {
+	return &languageCounter{counts: map[string]int{}}
+}
+
+func (lc *languageCounter) add(languages []string) {
+	if lc == nil {
+		return
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for _, language := range languages {
+		lc.counts[language]++
+	}
+}
+
+// summary returns the counts as "it: 10, en: 4", the most offered
+// languages first, or "" if there are none.
+func (lc *languageCounter) summary() string {
+	if lc == nil {
+		return ""
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	languages := slices.SortedFunc(maps.Keys(lc.counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(lc.counts[b], lc.counts[a]), cmp.Compare(a, b))
+	})
+
+	parts := make([]string, 0, len(languages))
+	for _, language := range languages {
+		parts = append(parts, fmt.Sprintf("%s: %d", language, lc.counts[language]))
+	}
+
+	return strings.Join(parts, ", ")
+}
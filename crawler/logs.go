@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/validation"
+)
+
+// RepositoryLog is the log of the processing of a repository.
+type RepositoryLog struct {
+	Repository string
+	CatalogID  string
+	// SoftwareID is the software of the repository in the API, "" if there's
+	// none.
+	SoftwareID string
+	// Text is the human readable log.
+	Text string
+	// Validation has the errors and warnings on the repository's
+	// publiccode.yml, if it got as far as validating it.
+	Validation []validation.Entry
+}
+
+// LogSink receives the logs of the processed repositories.
+type LogSink interface {
+	Log(ctx context.Context, entry RepositoryLog) error
+}
+
+// apiLogSink posts the logs to the API, with the validation entries as JSON
+// after the text (see validation.FormatMessage).
+type apiLogSink struct {
+	client apiclient.Client
+}
+
+func (s apiLogSink) Log(ctx context.Context, entry RepositoryLog) error {
+	message := validation.FormatMessage(entry.Text, entry.Validation)
+
+	switch {
+	case entry.CatalogID != "" && entry.SoftwareID != "":
+		return s.client.PostCatalogSoftwareLog(ctx, entry.CatalogID, entry.SoftwareID, message)
+	case entry.CatalogID != "":
+		return s.client.PostCatalogLog(ctx, entry.CatalogID, message)
+	case entry.SoftwareID != "":
+		return s.client.PostSoftwareLog(ctx, entry.SoftwareID, message)
+	default:
+		return s.client.PostLog(ctx, message)
+	}
+}
+
+// SetLogSink makes the crawler send the repository logs to sink instead of
+// the API.
+func (c *Crawler) SetLogSink(sink LogSink) {
+	c.logSink = sink
+}
+
+func (c *Crawler) logs() LogSink {
+	if c.logSink == nil {
+		return apiLogSink{client: c.apiClient}
+	}
+
+	return c.logSink
+}
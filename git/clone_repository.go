@@ -17,6 +17,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/italia/publiccode-crawler/v4/common"
 	"github.com/italia/publiccode-crawler/v4/git/vitality"
+	"github.com/italia/publiccode-crawler/v4/internal"
 	"github.com/italia/publiccode-crawler/v4/metrics"
 	"github.com/spf13/viper"
 )
@@ -50,9 +51,11 @@ func CloneRepository(hostname, name, gitURL, index string) error {
 		args = append(args, "--shallow-since="+existing.LastUpdated.Format("2006-01-02"))
 	}
 
-	args = append(args, gitURL, tmpDir)
+	args = append(args, "--", gitURL, tmpDir)
 
 	cmd := exec.CommandContext(context.Background(), "git", args...)
+	cmd.Env = internal.GitEnv()
+
 	if out, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("cannot clone %s: %w: %s", gitURL, err, out)
 	}
@@ -0,0 +1,13 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Credential returns the secret configured for key (usually a hostname) in
+// the CREDENTIALS table, or an empty string if there is none.
+func Credential(key string) string {
+	return viper.GetStringMapString("CREDENTIALS")[strings.ToLower(key)]
+}
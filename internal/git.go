@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// gitAllowedProtocols are the protocols git may reach remotes with, unless
+// GIT_ALLOW_PROTOCOL is set in the environment. Remotes come from publishers
+// and catalogs, so local paths and file:// are refused.
+const gitAllowedProtocols = "http:https"
+
+// gitWaitDelay is how long to wait for the helpers git runs, eg.
+// git-remote-https, to close the output once git is killed.
+const gitWaitDelay = 5 * time.Second
+
+// GitEnv returns the environment to run git on remotes with: it never
+// prompts for credentials and only reaches remotes over HTTP(S).
+func GitEnv() []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if _, ok := os.LookupEnv("GIT_ALLOW_PROTOCOL"); !ok {
+		env = append(env, "GIT_ALLOW_PROTOCOL="+gitAllowedProtocols)
+	}
+
+	return env
+}
+
+// RunGit runs git with args in dir (if not empty), with the GitEnv
+// environment, and returns its standard output. It's killed after
+// GIT_TIMEOUT, so that a stalled server can't block the crawl.
+func RunGit(dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	ctx := context.Background()
+
+	timeout := viper.GetDuration("GIT_TIMEOUT")
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = GitEnv()
+	cmd.WaitDelay = gitWaitDelay
+
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("git %s: timed out after %s", args[0], timeout)
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return out, nil
+}
+
+// GitDefaultBranch returns the branch HEAD points to in the remote repository.
+func GitDefaultBranch(remote string) (string, error) {
+	out, err := RunGit("", "ls-remote", "--symref", "--", remote, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	for line := range strings.SplitSeq(string(out), "\n") {
+		ref, found := strings.CutPrefix(line, "ref: ")
+		if !found {
+			continue
+		}
+
+		ref, _, _ = strings.Cut(ref, "\t")
+
+		return strings.TrimPrefix(ref, "refs/heads/"), nil
+	}
+
+	return "", fmt.Errorf("can't find the default branch of %s (empty repo?)", remote)
+}
+
+// GitBranchCommit returns the commit at the tip of branch in the remote
+// repository.
+func GitBranchCommit(remote, branch string) (string, error) {
+	out, err := RunGit("", "ls-remote", "--", remote, "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+
+	commit, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	if commit == "" {
+		return "", fmt.Errorf("no branch %q in %s", branch, remote)
+	}
+
+	return commit, nil
+}
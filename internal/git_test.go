@@ -0,0 +1,74 @@
+package internal_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/internal"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitRemoteIsNotAnOption(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	remote := "--upload-pack=touch " + marker + ";"
+
+	_, err := internal.GitDefaultBranch(remote)
+	require.Error(t, err)
+
+	_, err = internal.GitBranchCommit(remote, "main")
+	require.Error(t, err)
+
+	assert.NoFileExists(t, marker, "the remote was run as a git option")
+}
+
+func TestGitOnlyReachesHTTP(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repoDir := t.TempDir()
+	_, err := internal.RunGit(repoDir, "init", "--quiet", "--initial-branch=main")
+	require.NoError(t, err)
+
+	remote := url.URL{Scheme: "file", Path: repoDir}
+
+	_, err = internal.GitDefaultBranch(remote.String())
+	require.ErrorContains(t, err, "not allowed")
+
+	_, err = internal.GitDefaultBranch(repoDir)
+	require.ErrorContains(t, err, "not allowed")
+}
+
+func TestRunGitTimeout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	viper.Set("GIT_TIMEOUT", "200ms")
+	t.Cleanup(func() { viper.Set("GIT_TIMEOUT", nil) })
+
+	start := time.Now()
+
+	_, err := internal.GitDefaultBranch(srv.URL + "/stalled.git")
+	require.ErrorContains(t, err, "timed out")
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,135 @@
+package linkcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of the check of a link.
+type Result struct {
+	// Status is the HTTP status code, 0 if there was no reply.
+	Status int `json:"status,omitempty"`
+	// Error is why there was no reply.
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// OK reports whether the link is reachable.
+func (r Result) OK() bool {
+	return r.Error == "" && r.Status > 0 && r.Status < http.StatusBadRequest
+}
+
+// Reason describes why the link isn't reachable.
+func (r Result) Reason() string {
+	if r.Error != "" {
+		return r.Error
+	}
+
+	return fmt.Sprintf("HTTP %d %s", r.Status, http.StatusText(r.Status))
+}
+
+// cache keeps the results by URL, in memory and in a JSON file.
+type cache struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	results map[string]Result
+}
+
+// loadCache loads the cache at path, if any, dropping the expired results.
+func loadCache(path string, ttl time.Duration) (*cache, error) {
+	c := &cache{path: path, ttl: ttl, results: map[string]Result{}}
+
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't read link check cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.results); err != nil {
+		return nil, fmt.Errorf("invalid link check cache %s: %w", path, err)
+	}
+
+	for link, result := range c.results {
+		if c.expired(result) {
+			delete(c.results, link)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *cache) expired(result Result) bool {
+	return time.Since(result.CheckedAt) > c.ttl
+}
+
+func (c *cache) get(link string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.results[link]
+	if !ok || c.expired(result) {
+		return Result{}, false
+	}
+
+	return result, true
+}
+
+func (c *cache) put(link string, result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[link] = result
+}
+
+// save writes the cache to a file beside it, then renames it over the old
+// one.
+func (c *cache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.results, "", "  ")
+	c.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("can't save link check cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("can't save link check cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("can't save link check cache %s: %w", c.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package linkcheck checks that the links in publiccode.yml files, such as
+// logos, screenshots and documentation, are still reachable.
+//
+// Results are cached in a JSON file, so that crawls don't check the same
+// links over and over.
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/validation"
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"github.com/spf13/viper"
+)
+
+// CacheFileName is the name of the link check cache in DATADIR.
+const CacheFileName = "linkcheck.json"
+
+// RuleBrokenLink is the rule ID of the warnings on broken links.
+const RuleBrokenLink = "broken-link"
+
+// Link is a link in publiccode.yml.
+type Link struct {
+	// Key is the path of the key with the link, eg. "description.en.documentation".
+	Key string
+	URL string
+}
+
+// Links returns the links to check in parsed: the logo, landingURL, roadmap
+// and the screenshots, documentation and apiDocumentation of each language.
+// Relative paths are resolved against baseURL, the directory publiccode.yml
+// is in, and skipped if it's nil.
+func Links(parsed publiccode.PublicCodeV0, baseURL *url.URL) []Link {
+	var links []Link
+
+	add := func(key, link string) {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			return
+		}
+
+		u, err := url.Parse(link)
+		if err != nil {
+			links = append(links, Link{Key: key, URL: link})
+
+			return
+		}
+
+		if !u.IsAbs() {
+			if baseURL == nil {
+				return
+			}
+
+			base := *baseURL
+			base.Path = strings.TrimSuffix(base.Path, "/") + "/"
+			u = base.ResolveReference(u)
+		}
+
+		links = append(links, Link{Key: key, URL: u.String()})
+	}
+
+	addURL := func(key string, u *publiccode.URL) {
+		if u != nil {
+			add(key, u.String())
+		}
+	}
+
+	if parsed.Logo != nil {
+		add("logo", *parsed.Logo)
+	}
+
+	addURL("landingURL", parsed.LandingURL)
+	addURL("roadmap", parsed.Roadmap)
+
+	languages := make([]string, 0, len(parsed.Description))
+	for language := range parsed.Description {
+		languages = append(languages, language)
+	}
+
+	slices.Sort(languages)
+
+	for _, language := range languages {
+		desc := parsed.Description[language]
+		prefix := "description." + language + "."
+
+		for i, screenshot := range desc.Screenshots {
+			add(fmt.Sprintf("%sscreenshots.%d", prefix, i), screenshot)
+		}
+
+		addURL(prefix+"documentation", desc.Documentation)
+		addURL(prefix+"apiDocumentation", desc.APIDocumentation)
+	}
+
+	return links
+}
+
+// Options configure a Checker.
+type Options struct {
+	// Concurrency is how many links are checked at once, across all the
+	// Check calls.
+	Concurrency int
+	// Timeout is the timeout of each check.
+	Timeout time.Duration
+	// CacheTTL is how long the results are reused before checking the
+	// links again.
+	CacheTTL time.Duration
+	// CachePath is the cache file, "" to not persist the results.
+	CachePath string
+}
+
+// OptionsFromConfig returns the Options in the LINK_CHECK_* configuration,
+// with the cache in DATADIR.
+func OptionsFromConfig() Options {
+	return Options{
+		Concurrency: viper.GetInt("LINK_CHECK_CONCURRENCY"),
+		Timeout:     viper.GetDuration("LINK_CHECK_TIMEOUT"),
+		CacheTTL:    viper.GetDuration("LINK_CHECK_CACHE_TTL"),
+		CachePath:   filepath.Join(viper.GetString("DATADIR"), CacheFileName),
+	}
+}
+
+// Checker checks links. It's safe for concurrent use.
+type Checker struct {
+	client *http.Client
+	cache  *cache
+	slots  chan struct{}
+}
+
+// New returns a Checker, loading the cache at opts.CachePath.
+func New(opts Options) (*Checker, error) {
+	cache, err := loadCache(opts.CachePath, opts.CacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checker{
+		client: &http.Client{Timeout: opts.Timeout},
+		cache:  cache,
+		slots:  make(chan struct{}, max(opts.Concurrency, 1)),
+	}, nil
+}
+
+// Save writes the cache to disk.
+func (c *Checker) Save() error {
+	return c.cache.save()
+}
+
+// Check checks links concurrently and returns a warning for each broken
+// one, in the order of links.
+func (c *Checker) Check(ctx context.Context, links []Link) []validation.Entry {
+	results := make([]Result, len(links))
+	done := make(chan struct{}, len(links))
+
+	for i, link := range links {
+		go func() {
+			results[i] = c.check(ctx, link.URL)
+			done <- struct{}{}
+		}()
+	}
+
+	for range links {
+		<-done
+	}
+
+	// Links not checked because the crawl was cancelled aren't broken.
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	var entries []validation.Entry
+
+	for i, link := range links {
+		if results[i].OK() {
+			continue
+		}
+
+		entries = append(entries, validation.Entry{
+			Key:      link.Key,
+			Severity: validation.SeverityWarning,
+			Message:  fmt.Sprintf("'%s' is not reachable: %s", link.URL, results[i].Reason()),
+			RuleID:   RuleBrokenLink,
+		})
+	}
+
+	return entries
+}
+
+// check returns the cached result for link or checks it.
+func (c *Checker) check(ctx context.Context, link string) Result {
+	if result, ok := c.cache.get(link); ok {
+		return result
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Result{Error: ctx.Err().Error()}
+	}
+	defer func() { <-c.slots }()
+
+	// Another check of the same link may have finished while waiting.
+	if result, ok := c.cache.get(link); ok {
+		return result
+	}
+
+	result := c.fetch(ctx, link)
+	if ctx.Err() == nil {
+		c.cache.put(link, result)
+	}
+
+	return result
+}
+
+// fetch checks link with a HEAD request, then with a GET if the server
+// refuses HEAD.
+func (c *Checker) fetch(ctx context.Context, link string) Result {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return Result{Error: "not an http(s) URL", CheckedAt: time.Now()}
+	}
+
+	result := c.request(ctx, http.MethodHead, link)
+	if result.Error == "" && result.Status >= http.StatusBadRequest {
+		result = c.request(ctx, http.MethodGet, link)
+	}
+
+	return result
+}
+
+func (c *Checker) request(ctx context.Context, method, link string) Result {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return Result{Error: err.Error(), CheckedAt: time.Now()}
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return Result{Error: err.Error(), CheckedAt: time.Now()}
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	return Result{Status: res.StatusCode, CheckedAt: time.Now()}
+}
@@ -0,0 +1,161 @@
+package linkcheck_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/linkcheck"
+	"github.com/italia/publiccode-crawler/v4/validation"
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseURL(t *testing.T, raw string) *publiccode.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+
+	return (*publiccode.URL)(u)
+}
+
+func TestLinks(t *testing.T) {
+	logo := "img/logo.png"
+
+	var parsed publiccode.PublicCodeV0
+	parsed.Logo = &logo
+	parsed.LandingURL = mustParseURL(t, "https://example.org/")
+	parsed.Description = map[string]publiccode.DescV0{
+		"it": {
+			Screenshots:   []string{"https://example.org/1.png", "shots/2.png"},
+			Documentation: mustParseURL(t, "https://docs.example.org/"),
+		},
+		"en": {APIDocumentation: mustParseURL(t, "https://api.example.org/")},
+	}
+
+	base, err := url.Parse("https://raw.example.org/acme/app/main")
+	require.NoError(t, err)
+
+	assert.Equal(t, []linkcheck.Link{
+		{Key: "logo", URL: "https://raw.example.org/acme/app/main/img/logo.png"},
+		{Key: "landingURL", URL: "https://example.org/"},
+		{Key: "description.en.apiDocumentation", URL: "https://api.example.org/"},
+		{Key: "description.it.screenshots.0", URL: "https://example.org/1.png"},
+		{Key: "description.it.screenshots.1", URL: "https://raw.example.org/acme/app/main/shots/2.png"},
+		{Key: "description.it.documentation", URL: "https://docs.example.org/"},
+	}, linkcheck.Links(parsed, base))
+
+	// Relative paths are skipped without a base URL.
+	assert.Len(t, linkcheck.Links(parsed, nil), 4)
+}
+
+// standIn serves /ok, /no-head (which refuses HEAD) and 404 for anything
+// else, counting the requests by path.
+func standIn(t *testing.T) (*httptest.Server, func(string) int) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/ok":
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/no-head":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return requests[path]
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv, requests := standIn(t)
+	cachePath := filepath.Join(t.TempDir(), linkcheck.CacheFileName)
+
+	opts := linkcheck.Options{Concurrency: 2, Timeout: time.Second, CacheTTL: time.Hour, CachePath: cachePath}
+
+	checker, err := linkcheck.New(opts)
+	require.NoError(t, err)
+
+	links := []linkcheck.Link{
+		{Key: "logo", URL: srv.URL + "/ok"},
+		{Key: "roadmap", URL: srv.URL + "/no-head"},
+		{Key: "landingURL", URL: srv.URL + "/gone"},
+		{Key: "description.it.documentation", URL: "ftp://example.org/doc"},
+	}
+
+	expected := []validation.Entry{
+		{
+			Key: "landingURL", Severity: validation.SeverityWarning,
+			Message: "'" + srv.URL + "/gone' is not reachable: HTTP 404 Not Found", RuleID: linkcheck.RuleBrokenLink,
+		},
+		{
+			Key: "description.it.documentation", Severity: validation.SeverityWarning,
+			Message: "'ftp://example.org/doc' is not reachable: not an http(s) URL", RuleID: linkcheck.RuleBrokenLink,
+		},
+	}
+
+	assert.Equal(t, expected, checker.Check(t.Context(), links))
+	assert.Equal(t, 1, requests("/ok"))
+	assert.Equal(t, 2, requests("/no-head"), "HEAD, then GET")
+
+	// The results come from the cache, in memory and on disk.
+	assert.Equal(t, expected, checker.Check(t.Context(), links))
+	require.NoError(t, checker.Save())
+
+	reloaded, err := linkcheck.New(opts)
+	require.NoError(t, err)
+	assert.Equal(t, expected, reloaded.Check(t.Context(), links))
+	assert.Equal(t, 1, requests("/ok"))
+	assert.Equal(t, 2, requests("/gone"))
+
+	// Expired results are checked again.
+	opts.CacheTTL = 0
+	expired, err := linkcheck.New(opts)
+	require.NoError(t, err)
+	expired.Check(t.Context(), links[:1])
+	assert.Equal(t, 2, requests("/ok"))
+}
+
+func TestCheck_cancelled(t *testing.T) {
+	srv, _ := standIn(t)
+
+	checker, err := linkcheck.New(linkcheck.Options{Concurrency: 1, Timeout: time.Second, CacheTTL: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	assert.Empty(t, checker.Check(ctx, []linkcheck.Link{{Key: "logo", URL: srv.URL + "/gone"}}))
+}
+
+func TestNew_invalidCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), linkcheck.CacheFileName)
+	require.NoError(t, os.WriteFile(cachePath, []byte("{"), 0o600))
+
+	_, err := linkcheck.New(linkcheck.Options{CachePath: cachePath})
+	assert.Error(t, err)
+}
@@ -21,8 +21,22 @@ func main() {
 
 	viper.SetDefault("DATADIR", "./data")
 	viper.SetDefault("ACTIVITY_DAYS", 60)
+	viper.SetDefault("API_BACKEND", "http")
 	viper.SetDefault("API_BASEURL", "https://api.developers.italia.it/v1/")
+	viper.SetDefault("API_RETRY_MAX", 4)
+	viper.SetDefault("API_RETRY_WAIT_MIN", "1s")
+	viper.SetDefault("API_RETRY_WAIT_MAX", "30s")
+	viper.SetDefault("API_RETRY_AFTER_MAX", "2m")
+	viper.SetDefault("API_TIMEOUT", "1m")
+	viper.SetDefault("API_BREAKER_THRESHOLD", 10)
+	viper.SetDefault("API_BREAKER_COOLDOWN", "1m")
 	viper.SetDefault("MAIN_PUBLISHER_ID", "")
+	viper.SetDefault("RULES_FILE", "")
+	viper.SetDefault("LINK_CHECK", false)
+	viper.SetDefault("LINK_CHECK_CONCURRENCY", 8)
+	viper.SetDefault("LINK_CHECK_TIMEOUT", "15s")
+	viper.SetDefault("LINK_CHECK_CACHE_TTL", "24h")
+	viper.SetDefault("GIT_TIMEOUT", "5m")
 	viper.SetDefault("GITHUB_TOKEN", "")
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -0,0 +1,3 @@
+// Package publishers provides tools working on lists of publishers, as read
+// from publishers YAML files or from the API, outside of a crawl.
+package publishers
@@ -0,0 +1,330 @@
+package publishers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/registry"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+)
+
+// Severity is how serious an Issue is. Errors make a crawl skip or misplace
+// sources, warnings are likely mistakes.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found by Lint in a publisher or one of its sources.
+type Issue struct {
+	Severity  Severity `json:"severity"`
+	Publisher string   `json:"publisher,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Message   string   `json:"message"`
+}
+
+func (issue Issue) String() string {
+	var b strings.Builder
+
+	b.WriteString(string(issue.Severity) + ": ")
+
+	if issue.Publisher != "" {
+		b.WriteString("[" + issue.Publisher + "] ")
+	}
+
+	if issue.URL != "" {
+		b.WriteString(issue.URL + ": ")
+	}
+
+	b.WriteString(issue.Message)
+
+	return b.String()
+}
+
+// LintOptions configures Lint.
+type LintOptions struct {
+	// Online makes Lint check that each source resolves through its driver,
+	// which means listing the groups and scanning the repositories.
+	Online bool
+	// Detect returns the driver for sources without one in online mode,
+	// like the crawler does. Those sources are skipped if it's nil.
+	Detect func(u url.URL) (string, error)
+}
+
+// alternativeIDRe matches the alternative IDs the crawler can append to a
+// publishers namespace, like IPA codes.
+var alternativeIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Lint checks publishers for the mistakes that would otherwise only show up
+// during a crawl and returns the issues found, in the order of publishers.
+func Lint(publishers []common.Publisher, opts LintOptions) []Issue {
+	var issues []Issue
+
+	ids := map[string]bool{}
+	// owners maps the normalized source URLs to the publishers having them.
+	owners := map[string]string{}
+
+	for _, publisher := range publishers {
+		name := publisher.ID
+		if name == "" {
+			name = publisher.Name
+		}
+
+		report := func(severity Severity, u string, format string, args ...any) {
+			issues = append(issues, Issue{
+				Severity:  severity,
+				Publisher: name,
+				URL:       u,
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+
+		switch {
+		case publisher.ID == "":
+			report(SeverityError, "", "missing id")
+		case ids[publisher.ID]:
+			report(SeverityError, "", "duplicate id %q", publisher.ID)
+		}
+
+		ids[publisher.ID] = true
+
+		if alt := publisher.AlternativeID; alt != "" && !alternativeIDRe.MatchString(alt) {
+			if strings.Contains(alt, ":") {
+				report(SeverityError, "", "malformed alternativeId %q, expected only the code, without namespace", alt)
+			} else {
+				report(SeverityError, "", "malformed alternativeId %q", alt)
+			}
+		}
+
+		if len(publisher.Sources) == 0 {
+			report(SeverityWarning, "", "no sources")
+		}
+
+		for _, source := range publisher.Sources {
+			u := source.URL.String()
+
+			key := NormalizeURL(source.URL)
+			switch owner, seen := owners[key]; {
+			case !seen:
+				owners[key] = name
+			case owner == name:
+				report(SeverityWarning, u, "listed more than once")
+			default:
+				report(SeverityError, u, "also listed under publisher %s", owner)
+			}
+
+			if source.URL.Scheme == "" || source.URL.Host == "" {
+				report(SeverityError, u, "not an absolute URL")
+
+				continue
+			}
+
+			sourceIssues := lintSource(source)
+			for _, issue := range sourceIssues {
+				report(issue.Severity, u, "%s", issue.Message)
+			}
+
+			if opts.Online && !HasErrors(sourceIssues) {
+				if err := resolve(source, publisher, opts.Detect); err != nil {
+					report(SeverityError, u, "%s", err.Error())
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// HasErrors reports whether any of issues is an error.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lintSource checks a single source. Only Severity and Message are set in
+// the returned issues.
+func lintSource(source common.CodeHosting) []Issue {
+	if source.Driver == "" {
+		return []Issue{{
+			Severity: SeverityWarning,
+			Message:  "no driver can be inferred from the URL, the crawler will have to probe the host",
+		}}
+	}
+
+	if !registry.IsHost(source.Driver) {
+		return []Issue{{Severity: SeverityError, Message: fmt.Sprintf("unknown driver %q", source.Driver)}}
+	}
+
+	host, err := registry.NewHost(source.Driver, registry.Config{URL: source.URL, Args: source.Args})
+	if err != nil {
+		return []Issue{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	switch {
+	case source.Group && host.Lister == nil:
+		return []Issue{{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("driver %q can't list the repositories of a group", source.Driver),
+		}}
+	case !source.Group && host.Scanner == nil:
+		return []Issue{{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("driver %q can't scan a single repository", source.Driver),
+		}}
+	}
+
+	var issues []Issue
+
+	switch shape := urlShape(source.Driver, source.URL); {
+	case source.Group && shape == shapeRepo:
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Message:  "listed as an organization, but looks like a repository URL",
+		})
+	case !source.Group && shape == shapeGroup:
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Message:  "listed as a repository, but looks like an organization URL",
+		})
+	}
+
+	return issues
+}
+
+type shape int
+
+const (
+	shapeUnknown shape = iota
+	shapeGroup
+	shapeRepo
+)
+
+// urlShape guesses whether u points to a group or a repository from the
+// layout of the driver's URLs.
+func urlShape(driver string, u url.URL) shape {
+	path := strings.Trim(u.Path, "/")
+
+	var segments []string
+	if path != "" {
+		segments = strings.Split(path, "/")
+	}
+
+	switch driver {
+	case "github", "bitbucket", "gitea", "forgejo":
+		switch len(segments) {
+		case 0:
+			// The root of a Gitea instance lists all its repositories.
+			if driver == "gitea" || driver == "forgejo" {
+				return shapeGroup
+			}
+		case 1:
+			return shapeGroup
+		case 2:
+			return shapeRepo
+		}
+	case "gitlab":
+		// Groups can be nested, so only the extremes are certain.
+		switch {
+		case strings.HasSuffix(path, ".git"):
+			return shapeRepo
+		case len(segments) == 0 && !strings.EqualFold(u.Hostname(), "gitlab.com"), len(segments) == 1:
+			return shapeGroup
+		}
+	case "sourcehut":
+		if len(segments) > 0 && strings.HasPrefix(segments[0], "~") {
+			switch len(segments) {
+			case 1:
+				return shapeGroup
+			case 2:
+				return shapeRepo
+			}
+		}
+	case "azuredevops":
+		for _, segment := range segments {
+			if segment == "_git" {
+				return shapeRepo
+			}
+		}
+
+		return shapeGroup
+	case "git":
+		return shapeRepo
+	}
+
+	return shapeUnknown
+}
+
+// NormalizeURL returns u in a form where the URLs of the same source
+// compare equal, regardless of scheme, case and trailing ".git" or "/".
+func NormalizeURL(u url.URL) string {
+	path := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+
+	return strings.ToLower(u.Host + path)
+}
+
+// resolve checks that source resolves through its driver, listing it if
+// it's a group or scanning it otherwise. A repository without publiccode.yml
+// still resolves.
+func resolve(source common.CodeHosting, publisher common.Publisher, detect func(url.URL) (string, error)) error {
+	driver := source.Driver
+	if driver == "" {
+		if detect == nil {
+			return nil
+		}
+
+		var err error
+		if driver, err = detect(source.URL); err != nil {
+			return fmt.Errorf("can't detect the driver: %w", err)
+		}
+
+		if driver == "" {
+			return errors.New("unrecognized platform")
+		}
+	}
+
+	host, err := registry.NewHost(driver, registry.Config{URL: source.URL, Args: source.Args, Filter: source.Filter})
+	if err != nil {
+		return err
+	}
+
+	repositories := make(chan common.Repository)
+	done := make(chan struct{})
+
+	go func() {
+		for range repositories { //nolint:revive // just draining
+		}
+
+		close(done)
+	}()
+
+	switch {
+	case source.Group && host.Lister != nil:
+		err = host.Lister.List(source.URL, publisher, repositories)
+	case !source.Group && host.Scanner != nil:
+		err = host.Scanner.Scan(source.URL, publisher, repositories)
+	}
+
+	close(repositories)
+	<-done
+
+	if errors.Is(err, scanner.ErrPubliccodeNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("doesn't resolve: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package publishers_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/publishers"
+	"github.com/italia/publiccode-crawler/v4/registry"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lintForge is a driver scanning only the repositories under /ok and
+// /no-publiccode, the latter without publiccode.yml.
+type lintForge struct{}
+
+func (lintForge) Scan(u url.URL, _ common.Publisher, _ chan common.Repository) error {
+	switch u.Path {
+	case "/ok":
+		return nil
+	case "/no-publiccode":
+		return scanner.ErrPubliccodeNotFound
+	default:
+		return errors.New("not found")
+	}
+}
+
+func init() {
+	registry.RegisterHost("lint-forge", func(registry.Config) (registry.Host, error) {
+		return registry.Host{Scanner: lintForge{}}, nil
+	})
+}
+
+func source(t *testing.T, rawURL, driver string, group bool) common.CodeHosting {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	return common.CodeHosting{URL: *u, Driver: driver, Group: group}
+}
+
+func messages(issues []publishers.Issue) []string {
+	out := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, issue.String())
+	}
+
+	return out
+}
+
+func TestLint(t *testing.T) {
+	pubs := []common.Publisher{
+		{
+			ID:            "comune-a",
+			AlternativeID: "c_a123",
+			Sources: []common.CodeHosting{
+				source(t, "https://github.com/comune-a", "github", true),
+				source(t, "https://github.com/comune-a/app", "github", true),
+				source(t, "https://github.com/comune-b", "github", false),
+				source(t, "https://code.example.org/app", "", false),
+			},
+		},
+		{
+			ID:            "comune-a",
+			AlternativeID: "urn:x-italian-pa:c_b123",
+			Sources: []common.CodeHosting{
+				source(t, "http://GitHub.com/comune-a/", "github", true),
+				source(t, "https://git.example.org/app.git", "git", true),
+				source(t, "https://forge.example.org/app", "myforge", false),
+			},
+		},
+		{
+			ID:            "comune-c",
+			AlternativeID: "c c",
+		},
+	}
+
+	issues := publishers.Lint(pubs, publishers.LintOptions{})
+
+	assert.Equal(t, []string{
+		"warning: [comune-a] https://github.com/comune-a/app: listed as an organization, but looks like a repository URL",
+		"warning: [comune-a] https://github.com/comune-b: listed as a repository, but looks like an organization URL",
+		"warning: [comune-a] https://code.example.org/app: " +
+			"no driver can be inferred from the URL, the crawler will have to probe the host",
+		`error: [comune-a] duplicate id "comune-a"`,
+		`error: [comune-a] malformed alternativeId "urn:x-italian-pa:c_b123", expected only the code, without namespace`,
+		"warning: [comune-a] http://GitHub.com/comune-a/: listed more than once",
+		`error: [comune-a] https://git.example.org/app.git: driver "git" can't list the repositories of a group`,
+		`error: [comune-a] https://forge.example.org/app: unknown driver "myforge"`,
+		`error: [comune-c] malformed alternativeId "c c"`,
+		"warning: [comune-c] no sources",
+	}, messages(issues))
+	assert.True(t, publishers.HasErrors(issues))
+}
+
+func TestLint_sharedURL(t *testing.T) {
+	pubs := []common.Publisher{
+		{ID: "a", Sources: []common.CodeHosting{source(t, "https://gitlab.com/shared/app", "gitlab", false)}},
+		{ID: "b", Sources: []common.CodeHosting{source(t, "https://gitlab.com/shared/app.git", "gitlab", false)}},
+	}
+
+	assert.Equal(t, []string{
+		"error: [b] https://gitlab.com/shared/app.git: also listed under publisher a",
+	}, messages(publishers.Lint(pubs, publishers.LintOptions{})))
+}
+
+func TestLint_clean(t *testing.T) {
+	pubs := []common.Publisher{{
+		ID:            "comune-a",
+		AlternativeID: "c_a123",
+		Sources: []common.CodeHosting{
+			source(t, "https://gitlab.com/comune-a/sub/app", "gitlab", false),
+			source(t, "https://git.sr.ht/~comune-a", "sourcehut", true),
+			source(t, "https://dev.azure.com/comune-a/project/_git/app", "azuredevops", false),
+			source(t, "https://gitea.example.org/", "gitea", true),
+		},
+	}}
+
+	issues := publishers.Lint(pubs, publishers.LintOptions{})
+	assert.Empty(t, issues)
+	assert.False(t, publishers.HasErrors(issues))
+}
+
+func TestLint_online(t *testing.T) {
+	pubs := []common.Publisher{{
+		ID: "a",
+		Sources: []common.CodeHosting{
+			source(t, "https://forge.example.org/ok", "lint-forge", false),
+			source(t, "https://forge.example.org/no-publiccode", "lint-forge", false),
+			source(t, "https://forge.example.org/missing", "lint-forge", false),
+			source(t, "https://unknown.example.org/missing", "", false),
+		},
+	}}
+
+	detect := func(u url.URL) (string, error) {
+		if u.Host == "unknown.example.org" {
+			return "", nil
+		}
+
+		return "lint-forge", nil
+	}
+
+	issues := publishers.Lint(pubs, publishers.LintOptions{Online: true, Detect: detect})
+
+	assert.Equal(t, []string{
+		"error: [a] https://forge.example.org/missing: doesn't resolve: not found",
+		"warning: [a] https://unknown.example.org/missing: " +
+			"no driver can be inferred from the URL, the crawler will have to probe the host",
+		"error: [a] https://unknown.example.org/missing: unrecognized platform",
+	}, messages(issues))
+}
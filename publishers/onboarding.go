@@ -0,0 +1,256 @@
+package publishers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Registration is a code hosting a publisher registered in the onboarding
+// portal.
+type Registration struct {
+	IPA string `json:"ipa" yaml:"ipa"`
+	URL string `json:"url" yaml:"url"`
+	PEC string `json:"pec" yaml:"pec"`
+}
+
+// registrations is the onboarding portal's list, in YAML or JSON.
+type registrations struct {
+	Registrati []Registration `json:"registrati" yaml:"registrati"`
+}
+
+// Registration list formats accepted by ParseRegistrations.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ParseRegistrations parses the onboarding portal's list of registrations in
+// format: YAML or JSON with a "registrati" list, or CSV with a header row
+// having at least the "ipa" and "url" columns.
+//
+// Every registration must have an IPA code and an absolute URL.
+func ParseRegistrations(data []byte, format string) ([]Registration, error) {
+	var (
+		regs []Registration
+		err  error
+	)
+
+	switch format {
+	case FormatYAML:
+		var list registrations
+		err = yaml.Unmarshal(data, &list)
+		regs = list.Registrati
+	case FormatJSON:
+		var list registrations
+		err = json.Unmarshal(data, &list)
+		regs = list.Registrati
+	case FormatCSV:
+		regs, err = parseRegistrationsCSV(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the %s list: %w", format, err)
+	}
+
+	for i, reg := range regs {
+		if reg.IPA == "" {
+			return nil, fmt.Errorf("registration %d (%s): missing ipa", i+1, reg.URL)
+		}
+
+		if u, err := url.Parse(reg.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("registration %d (%s): invalid url %q", i+1, reg.IPA, reg.URL)
+		}
+	}
+
+	return regs, nil
+}
+
+func parseRegistrationsCSV(data []byte) ([]Registration, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"ipa", "url"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var regs []Registration
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		regs = append(regs, Registration{
+			IPA: field(record, "ipa"),
+			URL: field(record, "url"),
+			PEC: field(record, "pec"),
+		})
+	}
+
+	return regs, nil
+}
+
+// ChangeKind is the kind of a Change made by Merge.
+type ChangeKind string
+
+const (
+	PublisherAdded   ChangeKind = "publisher added"
+	PublisherRemoved ChangeKind = "publisher removed"
+	SourceAdded      ChangeKind = "source added"
+	SourceRemoved    ChangeKind = "source removed"
+)
+
+// Change is a change made by Merge to a list of publishers.
+type Change struct {
+	Kind      ChangeKind `json:"kind"`
+	Publisher string     `json:"publisher"`
+	URL       string     `json:"url,omitempty"`
+}
+
+func (change Change) String() string {
+	if change.URL == "" {
+		return fmt.Sprintf("%s: %s", change.Kind, change.Publisher)
+	}
+
+	return fmt.Sprintf("%s: [%s] %s", change.Kind, change.Publisher, change.URL)
+}
+
+// Merge adds regs to publishers, matching publishers by ID with the IPA
+// codes. Registered URLs become group sources, unless the publisher already
+// has them: URLs are compared with NormalizeURL.
+//
+// With prune, publishers without registrations are removed, and so are the
+// sources of the registered publishers that are no longer registered.
+//
+// It returns the merged publishers and the changes made, without modifying
+// publishers.
+func Merge(publishers []common.Publisher, regs []Registration, prune bool) ([]common.Publisher, []Change) {
+	var changes []Change
+
+	merged := make([]common.Publisher, 0, len(publishers))
+	for _, publisher := range publishers {
+		publisher.Sources = slices.Clone(publisher.Sources)
+		merged = append(merged, publisher)
+	}
+
+	index := map[string]int{}
+	for i, publisher := range merged {
+		index[publisher.ID] = i
+	}
+
+	// registered maps the IPA codes to their normalized URLs.
+	registered := map[string]map[string]bool{}
+
+	for _, reg := range regs {
+		// Already validated by ParseRegistrations.
+		regURL, _ := url.Parse(reg.URL)
+		key := NormalizeURL(*regURL)
+
+		if registered[reg.IPA] == nil {
+			registered[reg.IPA] = map[string]bool{}
+		}
+
+		registered[reg.IPA][key] = true
+
+		source := common.CodeHosting{
+			URL:    *regURL,
+			Driver: common.InferVCSDriver(*regURL),
+			Group:  true,
+		}
+
+		i, ok := index[reg.IPA]
+		if !ok {
+			index[reg.IPA] = len(merged)
+			merged = append(merged, common.Publisher{
+				ID:      reg.IPA,
+				Name:    reg.IPA,
+				Sources: []common.CodeHosting{source},
+			})
+
+			changes = append(changes,
+				Change{Kind: PublisherAdded, Publisher: reg.IPA},
+				Change{Kind: SourceAdded, Publisher: reg.IPA, URL: reg.URL},
+			)
+
+			continue
+		}
+
+		hasSource := slices.ContainsFunc(merged[i].Sources, func(existing common.CodeHosting) bool {
+			return NormalizeURL(existing.URL) == key
+		})
+		if hasSource {
+			continue
+		}
+
+		merged[i].Sources = append(merged[i].Sources, source)
+		changes = append(changes, Change{Kind: SourceAdded, Publisher: reg.IPA, URL: reg.URL})
+	}
+
+	if !prune {
+		return merged, changes
+	}
+
+	pruned := merged[:0]
+
+	for _, publisher := range merged {
+		urls, ok := registered[publisher.ID]
+		if !ok {
+			changes = append(changes, Change{Kind: PublisherRemoved, Publisher: publisher.ID})
+
+			continue
+		}
+
+		publisher.Sources = slices.DeleteFunc(publisher.Sources, func(source common.CodeHosting) bool {
+			if urls[NormalizeURL(source.URL)] {
+				return false
+			}
+
+			changes = append(changes, Change{Kind: SourceRemoved, Publisher: publisher.ID, URL: source.URL.String()})
+
+			return true
+		})
+
+		pruned = append(pruned, publisher)
+	}
+
+	return pruned, changes
+}
@@ -0,0 +1,104 @@
+package publishers_test
+
+import (
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/publishers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistrations(t *testing.T) {
+	want := []publishers.Registration{
+		{IPA: "c_a", URL: "https://github.com/comune-a", PEC: "a@pec.example.org"},
+		{IPA: "c_b", URL: "https://gitlab.com/comune-b"},
+	}
+
+	for format, payload := range map[string]string{
+		publishers.FormatYAML: `registrati:
+  - ipa: c_a
+    url: https://github.com/comune-a
+    pec: a@pec.example.org
+  - ipa: c_b
+    url: https://gitlab.com/comune-b
+`,
+		publishers.FormatJSON: `{"registrati": [
+  {"ipa": "c_a", "url": "https://github.com/comune-a", "pec": "a@pec.example.org"},
+  {"ipa": "c_b", "url": "https://gitlab.com/comune-b"}
+]}`,
+		publishers.FormatCSV: "URL,IPA,PEC\n" +
+			"https://github.com/comune-a,c_a,a@pec.example.org\n" +
+			"https://gitlab.com/comune-b,c_b,\n",
+	} {
+		regs, err := publishers.ParseRegistrations([]byte(payload), format)
+		require.NoError(t, err, format)
+		assert.Equal(t, want, regs, format)
+	}
+}
+
+func TestParseRegistrations_invalid(t *testing.T) {
+	for _, tc := range []struct{ format, payload string }{
+		{publishers.FormatYAML, "registrati: [a"},
+		{publishers.FormatYAML, "registrati:\n  - url: https://github.com/a\n"},
+		{publishers.FormatJSON, `{"registrati": [{"ipa": "a", "url": "github.com/a"}]}`},
+		{publishers.FormatCSV, "ipa,pec\na,b\n"},
+		{publishers.FormatCSV, "ipa,url\na\n"},
+		{"xml", "<registrati/>"},
+	} {
+		_, err := publishers.ParseRegistrations([]byte(tc.payload), tc.format)
+		assert.Error(t, err, tc.payload)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	existing := []common.Publisher{
+		{ID: "c_a", Name: "Comune di A", Sources: []common.CodeHosting{
+			source(t, "https://github.com/comune-a/", "github", true),
+			source(t, "https://github.com/comune-a-old", "github", true),
+		}},
+		{ID: "c_old", Sources: []common.CodeHosting{source(t, "https://github.com/old", "github", true)}},
+	}
+
+	regs := []publishers.Registration{
+		{IPA: "c_a", URL: "http://GitHub.com/comune-a"},
+		{IPA: "c_a", URL: "https://gitlab.com/comune-a"},
+		{IPA: "c_b", URL: "https://github.com/comune-b"},
+		{IPA: "c_b", URL: "https://github.com/comune-b.git"},
+	}
+
+	merged, changes := publishers.Merge(existing, regs, false)
+
+	require.Len(t, merged, 3)
+	assert.Equal(t, "Comune di A", merged[0].Name)
+	assert.Len(t, merged[0].Sources, 3)
+	assert.Equal(t, "gitlab", merged[0].Sources[2].Driver)
+	assert.True(t, merged[0].Sources[2].Group)
+	assert.Equal(t, "c_b", merged[2].ID)
+	assert.Len(t, merged[2].Sources, 1)
+
+	assert.Equal(t, []publishers.Change{
+		{Kind: publishers.SourceAdded, Publisher: "c_a", URL: "https://gitlab.com/comune-a"},
+		{Kind: publishers.PublisherAdded, Publisher: "c_b"},
+		{Kind: publishers.SourceAdded, Publisher: "c_b", URL: "https://github.com/comune-b"},
+	}, changes)
+
+	// The existing publishers are left as they were.
+	assert.Len(t, existing, 2)
+	assert.Len(t, existing[0].Sources, 2)
+
+	pruned, changes := publishers.Merge(existing, regs, true)
+
+	require.Len(t, pruned, 2)
+	assert.Equal(t, "c_a", pruned[0].ID)
+	assert.Len(t, pruned[0].Sources, 2)
+	assert.Equal(t, "c_b", pruned[1].ID)
+
+	assert.Equal(t, []publishers.Change{
+		{Kind: publishers.SourceAdded, Publisher: "c_a", URL: "https://gitlab.com/comune-a"},
+		{Kind: publishers.PublisherAdded, Publisher: "c_b"},
+		{Kind: publishers.SourceAdded, Publisher: "c_b", URL: "https://github.com/comune-b"},
+		{Kind: publishers.SourceRemoved, Publisher: "c_a", URL: "https://github.com/comune-a-old"},
+		{Kind: publishers.PublisherRemoved, Publisher: "c_old"},
+	}, changes)
+}
@@ -0,0 +1,252 @@
+package publishers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/common"
+)
+
+// PublishersAPI is the part of the API client Push uses.
+type PublishersAPI interface {
+	GetAPIPublishers(ctx context.Context, all bool) ([]apiclient.Publisher, error)
+	PostPublisher(ctx context.Context, publisher apiclient.Publisher) (*apiclient.Publisher, error)
+	PatchPublisher(ctx context.Context, publisherID string, patch apiclient.PublisherPatch) error
+}
+
+// PushOptions configures Push.
+type PushOptions struct {
+	// DryRun computes the changes without making them.
+	DryRun bool
+	// Deactivate deactivates the active API publishers missing from the file.
+	Deactivate bool
+}
+
+// PushAction is what Push does to an API publisher.
+type PushAction string
+
+const (
+	PushCreate     PushAction = "create"
+	PushUpdate     PushAction = "update"
+	PushReactivate PushAction = "reactivate"
+	PushDeactivate PushAction = "deactivate"
+)
+
+// PushChange is a change made by Push to an API publisher.
+type PushChange struct {
+	Action PushAction `json:"action"`
+	// Publisher is the ID in the file or, for deactivations, in the API.
+	Publisher string `json:"publisher"`
+	// APIID is the ID of the publisher in the API, empty for creations in
+	// dry-run mode.
+	APIID string `json:"apiId,omitempty"`
+	// Fields are the updated fields.
+	Fields []string `json:"fields,omitempty"`
+	Err    error    `json:"-"`
+}
+
+func (change PushChange) String() string {
+	s := fmt.Sprintf("%s %s", change.Action, change.Publisher)
+
+	if change.APIID != "" && change.APIID != change.Publisher {
+		s += " (" + change.APIID + ")"
+	}
+
+	if len(change.Fields) > 0 {
+		s += fmt.Sprintf(" %v", change.Fields)
+	}
+
+	if change.Err != nil {
+		s += ": " + change.Err.Error()
+	}
+
+	return s
+}
+
+// Push reconciles the API with publishers: it creates the missing ones and
+// updates the description, alternative ID and code hostings of the changed
+// ones, reactivating them if needed.
+//
+// A publisher in the file matches an API publisher with its ID, or with its
+// alternative ID (or its ID when it has none) as API alternative ID. New
+// publishers get that as alternative ID, since the API assigns the IDs.
+//
+// It returns the changes, with the errors of those that failed, and an error
+// joining those errors or if the API publishers can't be listed.
+func Push(ctx context.Context, api PublishersAPI, publishers []common.Publisher, opts PushOptions) ([]PushChange, error) {
+	existing, err := api.GetAPIPublishers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		changes []PushChange
+		errs    []error
+	)
+
+	matched := map[string]bool{}
+
+	for _, publisher := range publishers {
+		desired := toAPIPublisher(publisher)
+
+		idx := slices.IndexFunc(existing, func(apiPublisher apiclient.Publisher) bool {
+			return apiPublisher.ID == publisher.ID ||
+				(apiPublisher.AlternativeID != "" && apiPublisher.AlternativeID == desired.AlternativeID)
+		})
+
+		if idx < 0 {
+			change := PushChange{Action: PushCreate, Publisher: publisher.ID}
+
+			if !opts.DryRun {
+				created, err := api.PostPublisher(ctx, desired)
+				if err != nil {
+					change.Err = err
+				} else {
+					change.APIID = created.ID
+				}
+			}
+
+			changes = append(changes, change)
+
+			continue
+		}
+
+		current := existing[idx]
+		matched[current.ID] = true
+
+		// Matching on the API ID, there's no alternative ID to set.
+		if current.ID == publisher.ID && publisher.AlternativeID == "" {
+			desired.AlternativeID = current.AlternativeID
+		}
+
+		patch, fields := diffPublisher(current, desired)
+		if len(fields) == 0 {
+			continue
+		}
+
+		change := PushChange{Action: PushUpdate, Publisher: publisher.ID, APIID: current.ID, Fields: fields}
+		if !current.Active {
+			change.Action = PushReactivate
+		}
+
+		if !opts.DryRun {
+			change.Err = api.PatchPublisher(ctx, current.ID, patch)
+		}
+
+		changes = append(changes, change)
+	}
+
+	if opts.Deactivate {
+		inactive := false
+
+		for _, current := range existing {
+			if matched[current.ID] || !current.Active {
+				continue
+			}
+
+			change := PushChange{Action: PushDeactivate, Publisher: current.ID, APIID: current.ID}
+
+			if !opts.DryRun {
+				change.Err = api.PatchPublisher(ctx, current.ID, apiclient.PublisherPatch{Active: &inactive})
+			}
+
+			changes = append(changes, change)
+		}
+	}
+
+	for _, change := range changes {
+		if change.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", change.Action, change.Publisher, change.Err))
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// toAPIPublisher returns publisher as an active API publisher.
+func toAPIPublisher(publisher common.Publisher) apiclient.Publisher {
+	alternativeID := publisher.AlternativeID
+	if alternativeID == "" {
+		alternativeID = publisher.ID
+	}
+
+	description := publisher.Name
+	if description == "" {
+		description = publisher.ID
+	}
+
+	apiPublisher := apiclient.Publisher{
+		ID:            publisher.ID,
+		AlternativeID: alternativeID,
+		Description:   description,
+		Active:        true,
+	}
+
+	for _, source := range publisher.Sources {
+		hosting := apiclient.CodeHosting{URL: source.URL.String(), Group: source.Group}
+
+		// The API infers the driver too, so it's only sent along with the
+		// arguments it would otherwise miss.
+		args := append(slices.Clone(source.Args), source.Filter.Args()...)
+		if len(args) > 0 || (source.Driver != "" && source.Driver != common.InferVCSDriver(source.URL)) {
+			hosting.Type = append([]string{source.Driver}, args...)
+		}
+
+		apiPublisher.CodeHostings = append(apiPublisher.CodeHostings, hosting)
+	}
+
+	return apiPublisher
+}
+
+// diffPublisher returns the patch turning current into desired and the names
+// of the fields it changes.
+func diffPublisher(current, desired apiclient.Publisher) (apiclient.PublisherPatch, []string) {
+	var (
+		patch  apiclient.PublisherPatch
+		fields []string
+	)
+
+	if current.Description != desired.Description {
+		patch.Description = &desired.Description
+		fields = append(fields, "description")
+	}
+
+	if current.AlternativeID != desired.AlternativeID {
+		patch.AlternativeID = &desired.AlternativeID
+		fields = append(fields, "alternativeId")
+	}
+
+	if !sameCodeHostings(current.CodeHostings, desired.CodeHostings) {
+		patch.CodeHostings = desired.CodeHostings
+		fields = append(fields, "codeHosting")
+	}
+
+	if !current.Active {
+		patch.Active = &desired.Active
+		fields = append(fields, "active")
+	}
+
+	return patch, fields
+}
+
+// sameCodeHostings reports whether a and b have the same code hostings,
+// ignoring their order and the timestamps.
+func sameCodeHostings(a, b []apiclient.CodeHosting) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, hosting := range a {
+		same := slices.ContainsFunc(b, func(other apiclient.CodeHosting) bool {
+			return hosting.URL == other.URL && hosting.Group == other.Group && slices.Equal(hosting.Type, other.Type)
+		})
+		if !same {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,152 @@
+package publishers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/publishers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePublishersAPI struct {
+	publishers []apiclient.Publisher
+	posted     []apiclient.Publisher
+	patched    map[string]apiclient.PublisherPatch
+	failPatch  string
+}
+
+func (api *fakePublishersAPI) GetAPIPublishers(_ context.Context, all bool) ([]apiclient.Publisher, error) {
+	if !all {
+		return nil, errors.New("inactive publishers not requested")
+	}
+
+	return api.publishers, nil
+}
+
+func (api *fakePublishersAPI) PostPublisher(_ context.Context, publisher apiclient.Publisher) (*apiclient.Publisher, error) {
+	api.posted = append(api.posted, publisher)
+	publisher.ID = "new-id"
+
+	return &publisher, nil
+}
+
+func (api *fakePublishersAPI) PatchPublisher(_ context.Context, publisherID string, patch apiclient.PublisherPatch) error {
+	if publisherID == api.failPatch {
+		return errors.New("HTTP 500")
+	}
+
+	if api.patched == nil {
+		api.patched = map[string]apiclient.PublisherPatch{}
+	}
+
+	api.patched[publisherID] = patch
+
+	return nil
+}
+
+func newFakePublishersAPI() *fakePublishersAPI {
+	return &fakePublishersAPI{publishers: []apiclient.Publisher{
+		{
+			ID: "uuid-a", AlternativeID: "c_a", Description: "Comune di A", Active: true,
+			CodeHostings: []apiclient.CodeHosting{{URL: "https://github.com/comune-a", Group: true}},
+		},
+		{
+			ID: "uuid-b", AlternativeID: "c_b", Description: "Comune di B", Active: false,
+			CodeHostings: []apiclient.CodeHosting{{URL: "https://github.com/comune-b", Group: true}},
+		},
+		{ID: "uuid-gone", AlternativeID: "c_gone", Description: "Gone", Active: true},
+		{ID: "uuid-inactive", Description: "Inactive", Active: false},
+	}}
+}
+
+func filePublishers(t *testing.T) []common.Publisher {
+	t.Helper()
+
+	return []common.Publisher{
+		{ID: "c_a", Name: "Comune di A", Sources: []common.CodeHosting{
+			source(t, "https://github.com/comune-a", "github", true),
+		}},
+		{ID: "c_b", Name: "Comune di B", Sources: []common.CodeHosting{
+			source(t, "https://github.com/comune-b", "github", true),
+			source(t, "https://git.comune-b.it/app.git", "git", false),
+		}},
+		{ID: "uuid-c", AlternativeID: "c_c", Name: "Comune di C"},
+		{ID: "c_d", Sources: []common.CodeHosting{source(t, "https://github.com/comune-d", "github", true)}},
+	}
+}
+
+func TestPush(t *testing.T) {
+	api := newFakePublishersAPI()
+	api.publishers = append(api.publishers, apiclient.Publisher{ID: "uuid-c", Description: "C", Active: true})
+
+	changes, err := publishers.Push(t.Context(), api, filePublishers(t), publishers.PushOptions{Deactivate: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"reactivate c_b (uuid-b) [codeHosting active]",
+		"update uuid-c [description alternativeId]",
+		"create c_d (new-id)",
+		"deactivate uuid-gone",
+	}, pushMessages(changes))
+
+	require.Len(t, api.posted, 1)
+	assert.Equal(t, apiclient.Publisher{
+		ID: "c_d", AlternativeID: "c_d", Description: "c_d", Active: true,
+		CodeHostings: []apiclient.CodeHosting{{URL: "https://github.com/comune-d", Group: true}},
+	}, api.posted[0])
+
+	patch := api.patched["uuid-b"]
+	assert.Equal(t, []apiclient.CodeHosting{
+		{URL: "https://github.com/comune-b", Group: true},
+		{URL: "https://git.comune-b.it/app.git", Type: []string{"git"}},
+	}, patch.CodeHostings)
+	require.NotNil(t, patch.Active)
+	assert.True(t, *patch.Active)
+	assert.Nil(t, patch.Description)
+
+	require.NotNil(t, api.patched["uuid-gone"].Active)
+	assert.False(t, *api.patched["uuid-gone"].Active)
+	assert.NotContains(t, api.patched, "uuid-inactive")
+	assert.NotContains(t, api.patched, "uuid-a")
+}
+
+func TestPush_dryRun(t *testing.T) {
+	api := newFakePublishersAPI()
+
+	changes, err := publishers.Push(t.Context(), api, filePublishers(t), publishers.PushOptions{DryRun: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"reactivate c_b (uuid-b) [codeHosting active]",
+		"create uuid-c",
+		"create c_d",
+	}, pushMessages(changes))
+	assert.Empty(t, api.posted)
+	assert.Empty(t, api.patched)
+}
+
+func TestPush_errors(t *testing.T) {
+	api := newFakePublishersAPI()
+	api.failPatch = "uuid-b"
+
+	changes, err := publishers.Push(t.Context(), api, filePublishers(t), publishers.PushOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reactivate c_b: HTTP 500")
+
+	// The other changes are still made.
+	assert.Len(t, changes, 3)
+	assert.Len(t, api.posted, 2)
+}
+
+func pushMessages(changes []publishers.PushChange) []string {
+	out := make([]string, 0, len(changes))
+	for _, change := range changes {
+		out = append(out, change.String())
+	}
+
+	return out
+}
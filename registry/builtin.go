@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterHost("github", withoutArgs(func(cfg Config) Host {
+		github := scanner.NewGitHubScanner().WithFilter(cfg.Filter)
+
+		return Host{Scanner: github, Lister: github}
+	}))
+	RegisterHost("gitlab", withoutArgs(func(cfg Config) Host {
+		gitlab := scanner.NewGitLabScanner().WithFilter(cfg.Filter)
+
+		return Host{Scanner: gitlab, Lister: gitlab}
+	}))
+	RegisterHost("bitbucket", withoutArgs(func(cfg Config) Host {
+		bitbucket := scanner.NewBitBucketScanner().WithFilter(cfg.Filter)
+
+		return Host{Scanner: bitbucket, Lister: bitbucket}
+	}))
+	RegisterHost("gitea", newGiteaHost)
+	RegisterHost("forgejo", newGiteaHost)
+	RegisterHost("sourcehut", withoutArgs(func(cfg Config) Host {
+		sourcehut := scanner.NewSourceHutScanner().WithFilter(cfg.Filter)
+
+		return Host{Scanner: sourcehut, Lister: sourcehut}
+	}))
+	RegisterHost("azuredevops", withoutArgs(func(cfg Config) Host {
+		azureDevOps := scanner.NewAzureDevOpsScanner().WithFilter(cfg.Filter)
+
+		return Host{Scanner: azureDevOps, Lister: azureDevOps}
+	}))
+	RegisterHost("git", withoutArgs(func(Config) Host {
+		return Host{Scanner: scanner.NewGitScanner()}
+	}))
+
+	RegisterCatalog("json", func(cfg Config) (Catalog, error) {
+		json, err := catalog.NewJSONDriverWithArgs(cfg.Args)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		return Catalog{Enumerator: json}, nil
+	})
+	RegisterCatalog("csv", func(cfg Config) (Catalog, error) {
+		csv, err := catalog.NewCSVDriverWithArgs(cfg.Args)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		return Catalog{Enumerator: csv}, nil
+	})
+	RegisterCatalog("yaml", func(cfg Config) (Catalog, error) {
+		yaml, err := catalog.NewYAMLDriverWithArgs(cfg.Args)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		return Catalog{Enumerator: yaml}, nil
+	})
+	RegisterCatalog("text", func(cfg Config) (Catalog, error) {
+		text, err := catalog.NewTextDriverWithArgs(cfg.Args)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		return Catalog{Enumerator: text}, nil
+	})
+	RegisterCatalog("gitrepo", func(cfg Config) (Catalog, error) {
+		cacheDir := filepath.Join(viper.GetString("DATADIR"), "catalogs")
+
+		gitrepo, err := catalog.NewGitRepoDriverWithArgs(cfg.Args, cacheDir)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		return Catalog{Enumerator: gitrepo}, nil
+	})
+	RegisterCatalog("software-catalog-api", newSoftwareAPICatalog)
+}
+
+// newSoftwareAPICatalog creates the driver federating another
+// developers-italia-api instance. By default the upstream software URLs are
+// scanned again, with the "import" argument their publiccode.yml files are
+// imported as they are.
+func newSoftwareAPICatalog(cfg Config) (Catalog, error) {
+	driver := catalog.NewSoftwareAPIDriver().WithFilter(cfg.Filter)
+
+	switch {
+	case len(cfg.Args) == 0:
+		return Catalog{Enumerator: driver}, nil
+	case len(cfg.Args) == 1 && cfg.Args[0] == "import":
+		return Catalog{Lister: driver}, nil
+	default:
+		return Catalog{}, fmt.Errorf("software-catalog-api driver: unsupported arguments %q", cfg.Args)
+	}
+}
+
+func newGiteaHost(cfg Config) (Host, error) {
+	gitea, err := scanner.NewGiteaScanner().WithFilter(cfg.Filter).WithArgs(cfg.Args)
+	if err != nil {
+		return Host{}, err
+	}
+
+	return Host{Scanner: gitea, Lister: gitea}, nil
+}
+
+// withoutArgs returns a factory for drivers that don't take arguments.
+// Args are ignored with a warning, for compatibility with sources that
+// have them.
+func withoutArgs(newHost func(cfg Config) Host) HostFactory {
+	return func(cfg Config) (Host, error) {
+		if len(cfg.Args) > 0 {
+			log.Warnf("[%s] ignoring arguments %q, the driver doesn't take any", cfg.URL.String(), cfg.Args)
+		}
+
+		return newHost(cfg), nil
+	}
+}
@@ -0,0 +1,189 @@
+// Package registry maps driver names to the factories creating code hosting
+// and catalog drivers.
+//
+// Drivers register themselves under a name, so that new forges and catalog
+// formats can be added, and downstream forks can compile in private drivers,
+// without changes to the crawler:
+//
+//	func init() {
+//		registry.RegisterHost("myforge", func(cfg registry.Config) (registry.Host, error) {
+//			s := NewMyForgeScanner(cfg.Args)
+//
+//			return registry.Host{Scanner: s, Lister: s}, nil
+//		})
+//	}
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+)
+
+// ErrUnknownDriver is returned when creating a driver with a name nothing
+// registered.
+var ErrUnknownDriver = errors.New("unknown driver")
+
+// Config is the per-source configuration a driver is created with. Listers
+// emit only the repositories matching Filter.
+type Config struct {
+	URL    url.URL
+	Args   []string
+	Filter common.RepoFilter
+}
+
+// Host is a code hosting driver. Lister is nil for drivers that can only scan
+// single repositories, Scanner for drivers that can only list.
+type Host struct {
+	Scanner scanner.Scanner
+	Lister  catalog.Lister
+}
+
+// Catalog is a catalog driver. Exactly one of Enumerator and Lister is set:
+// Enumerator for catalogs listing repository URLs, which the crawler then
+// scans through their code hosting driver, Lister for catalogs that already
+// provide the repositories' publiccode.yml.
+//
+// Publiccode is set for enumerating catalogs whose entries are the URLs of
+// publiccode.yml files rather than of repositories. Sources enable it with
+// the entries=publiccode argument, which works with every enumerating driver.
+type Catalog struct {
+	Enumerator catalog.Enumerator
+	Lister     catalog.Lister
+	Publiccode bool
+}
+
+// publiccodeEntriesArg is the argument making a catalog's entries
+// publiccode.yml URLs.
+const publiccodeEntriesArg = "entries=publiccode"
+
+// HostFactory creates a code hosting driver for a source.
+type HostFactory func(cfg Config) (Host, error)
+
+// CatalogFactory creates a catalog driver for a source.
+type CatalogFactory func(cfg Config) (Catalog, error)
+
+var (
+	mu       sync.RWMutex
+	hosts    = map[string]HostFactory{}
+	catalogs = map[string]CatalogFactory{}
+)
+
+// RegisterHost registers a code hosting driver under name.
+// It panics if a driver with the same name is already registered.
+func RegisterHost(name string, factory HostFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	mustBeUnregistered(name)
+
+	hosts[name] = factory
+}
+
+// RegisterCatalog registers a catalog driver under name.
+// It panics if a driver with the same name is already registered.
+func RegisterCatalog(name string, factory CatalogFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	mustBeUnregistered(name)
+
+	catalogs[name] = factory
+}
+
+// NewHost creates the code hosting driver registered under name.
+func NewHost(name string, cfg Config) (Host, error) {
+	mu.RLock()
+	factory, ok := hosts[name]
+	mu.RUnlock()
+
+	if !ok {
+		return Host{}, unknownDriverError(name)
+	}
+
+	return factory(cfg)
+}
+
+// NewCatalog creates the catalog driver registered under name.
+func NewCatalog(name string, cfg Config) (Catalog, error) {
+	mu.RLock()
+	factory, ok := catalogs[name]
+	mu.RUnlock()
+
+	if !ok {
+		return Catalog{}, unknownDriverError(name)
+	}
+
+	publiccode := slices.Contains(cfg.Args, publiccodeEntriesArg)
+	cfg.Args = slices.DeleteFunc(slices.Clone(cfg.Args), func(arg string) bool {
+		return arg == publiccodeEntriesArg
+	})
+
+	cat, err := factory(cfg)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	if publiccode {
+		if cat.Enumerator == nil {
+			return Catalog{}, fmt.Errorf("driver %q doesn't support %s", name, publiccodeEntriesArg)
+		}
+
+		cat.Publiccode = true
+	}
+
+	return cat, nil
+}
+
+// IsHost reports whether name is a registered code hosting driver.
+func IsHost(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := hosts[name]
+
+	return ok
+}
+
+// IsCatalog reports whether name is a registered catalog driver.
+func IsCatalog(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := catalogs[name]
+
+	return ok
+}
+
+// Names returns the sorted names of all the registered drivers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := slices.Collect(maps.Keys(hosts))
+	names = append(names, slices.Collect(maps.Keys(catalogs))...)
+	slices.Sort(names)
+
+	return names
+}
+
+func mustBeUnregistered(name string) {
+	_, host := hosts[name]
+	_, cat := catalogs[name]
+
+	if host || cat {
+		panic(fmt.Sprintf("registry: driver %q registered twice", name))
+	}
+}
+
+func unknownDriverError(name string) error {
+	return fmt.Errorf("%w %q (registered drivers: %s)", ErrUnknownDriver, name, strings.Join(Names(), ", "))
+}
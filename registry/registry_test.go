@@ -0,0 +1,115 @@
+package registry_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/catalog"
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeScanner struct {
+	args []string
+}
+
+func (s fakeScanner) Scan(url.URL, common.Publisher, chan common.Repository) error {
+	return nil
+}
+
+type fakeCatalog struct{}
+
+func (fakeCatalog) Enumerate(context.Context, url.URL) ([]catalog.Entry, error) {
+	return nil, nil
+}
+
+func init() {
+	registry.RegisterHost("test-forge", func(cfg registry.Config) (registry.Host, error) {
+		return registry.Host{Scanner: fakeScanner{args: cfg.Args}}, nil
+	})
+	registry.RegisterCatalog("test-catalog", func(registry.Config) (registry.Catalog, error) {
+		return registry.Catalog{Enumerator: fakeCatalog{}}, nil
+	})
+}
+
+func TestNewHost(t *testing.T) {
+	assert.True(t, registry.IsHost("test-forge"))
+	assert.False(t, registry.IsHost("test-catalog"))
+
+	host, err := registry.NewHost("test-forge", registry.Config{Args: []string{"a=b"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, fakeScanner{args: []string{"a=b"}}, host.Scanner)
+	assert.Nil(t, host.Lister)
+}
+
+func TestNewCatalog(t *testing.T) {
+	assert.True(t, registry.IsCatalog("test-catalog"))
+	assert.False(t, registry.IsCatalog("test-forge"))
+
+	cat, err := registry.NewCatalog("test-catalog", registry.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, fakeCatalog{}, cat.Enumerator)
+	assert.Nil(t, cat.Lister)
+}
+
+func TestBuiltinDrivers(t *testing.T) {
+	names := registry.Names()
+
+	for _, name := range []string{
+		"github", "gitlab", "bitbucket", "gitea", "forgejo",
+		"json", "csv", "yaml", "text", "software-catalog-api",
+	} {
+		assert.Contains(t, names, name)
+	}
+}
+
+func TestUnknownDriver(t *testing.T) {
+	_, err := registry.NewHost("nope", registry.Config{})
+	require.ErrorIs(t, err, registry.ErrUnknownDriver)
+	assert.Contains(t, err.Error(), `unknown driver "nope"`)
+	assert.Contains(t, err.Error(), "github, gitlab")
+
+	_, err = registry.NewCatalog("test-forge", registry.Config{})
+	require.ErrorIs(t, err, registry.ErrUnknownDriver)
+}
+
+func TestSoftwareAPICatalogArgs(t *testing.T) {
+	cat, err := registry.NewCatalog("software-catalog-api", registry.Config{})
+	require.NoError(t, err)
+	assert.NotNil(t, cat.Enumerator)
+	assert.Nil(t, cat.Lister)
+
+	cat, err = registry.NewCatalog("software-catalog-api", registry.Config{Args: []string{"import"}})
+	require.NoError(t, err)
+	assert.Nil(t, cat.Enumerator)
+	assert.NotNil(t, cat.Lister)
+
+	_, err = registry.NewCatalog("software-catalog-api", registry.Config{Args: []string{"bogus"}})
+	require.Error(t, err)
+}
+
+func TestPubliccodeEntries(t *testing.T) {
+	cat, err := registry.NewCatalog("text", registry.Config{Args: []string{"entries=publiccode"}})
+	require.NoError(t, err)
+	assert.True(t, cat.Publiccode)
+	assert.NotNil(t, cat.Enumerator)
+
+	cat, err = registry.NewCatalog("text", registry.Config{})
+	require.NoError(t, err)
+	assert.False(t, cat.Publiccode)
+
+	_, err = registry.NewCatalog("software-catalog-api", registry.Config{Args: []string{"import", "entries=publiccode"}})
+	require.Error(t, err)
+}
+
+func TestRegisterTwice(t *testing.T) {
+	assert.Panics(t, func() {
+		registry.RegisterHost("github", func(registry.Config) (registry.Host, error) {
+			return registry.Host{}, nil
+		})
+	})
+}
@@ -0,0 +1,283 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/italia/publiccode-crawler/v4/validation"
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Check evaluates rules on parsed and returns the failures, with the rule
+// ID as RuleID and the path of the failing key as Key.
+func Check(rules []Rule, parsed publiccode.PublicCodeV0) ([]validation.Entry, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	data, err := parsed.ToYAML()
+	if err != nil {
+		return nil, fmt.Errorf("can't check rules: %w", err)
+	}
+
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("can't check rules: %w", err)
+	}
+
+	// ToYAML only has the country section as the deprecated "IT", rules
+	// can use either.
+	if root, ok := tree.(map[string]any); ok && root["IT"] != nil {
+		root["it"] = root["IT"]
+	}
+
+	var entries []validation.Entry
+
+	for _, rule := range rules {
+		entries = append(entries, rule.check(tree)...)
+	}
+
+	return entries, nil
+}
+
+// match is a value found at path.
+type match struct {
+	path  string
+	value any
+}
+
+func (r Rule) check(tree any) []validation.Entry {
+	var entries []validation.Entry
+
+	if r.Languages != nil {
+		entries = append(entries, r.checkLanguages(tree)...)
+	}
+
+	if !r.checksKey() {
+		return entries
+	}
+
+	var found []match
+
+	var missing []string
+
+	lookup(tree, "", strings.Split(r.Key, "."), &found, &missing)
+
+	fail := func(path, message string) {
+		entries = append(entries, r.entry(path, r.Severity, message))
+	}
+
+	if r.Required {
+		for _, path := range missing {
+			fail(path, "is required")
+		}
+	}
+
+	for _, m := range found {
+		if isEmpty(m.value) {
+			if r.Required {
+				fail(m.path, "is required")
+			}
+
+			continue
+		}
+
+		if message := r.checkValue(m.value); message != "" {
+			fail(m.path, message)
+		}
+	}
+
+	return entries
+}
+
+// checkLanguages checks the description languages and their Fields: the
+// Required ones with the rule's severity, the Recommended ones as warnings.
+func (r Rule) checkLanguages(tree any) []validation.Entry {
+	var descriptions map[string]any
+	if root, ok := tree.(map[string]any); ok {
+		descriptions, _ = root["description"].(map[string]any)
+	}
+
+	var entries []validation.Entry
+
+	check := func(languages []string, severity validation.Severity, missing string) {
+		for _, language := range languages {
+			key, description := findLanguage(descriptions, language)
+			if key == "" {
+				entries = append(entries, r.entry("description."+language, severity, missing+" in "+language))
+
+				continue
+			}
+
+			for _, field := range r.Languages.Fields {
+				value, ok := description[field]
+				if !ok || isEmpty(value) {
+					entries = append(entries, r.entry("description."+key+"."+field, severity, missing+" in "+key))
+				}
+			}
+		}
+	}
+
+	check(r.Languages.Required, r.Severity, "is required")
+	check(r.Languages.Recommended, validation.SeverityWarning, "is recommended")
+
+	return entries
+}
+
+// entry returns a failure of r, with r.Message if set.
+func (r Rule) entry(key string, severity validation.Severity, message string) validation.Entry {
+	if r.Message != "" {
+		message = r.Message
+	}
+
+	return validation.Entry{Key: key, Severity: severity, Message: message, RuleID: r.ID}
+}
+
+// findLanguage returns the key and the description in language or in one
+// of its regional variants, or "" if there's none or it's empty.
+func findLanguage(descriptions map[string]any, language string) (string, map[string]any) {
+	keys := make([]string, 0, len(descriptions))
+	for key := range descriptions {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		base, _, _ := strings.Cut(key, "-")
+		if !strings.EqualFold(key, language) && !strings.EqualFold(base, language) {
+			continue
+		}
+
+		if description, ok := descriptions[key].(map[string]any); ok && !isEmpty(description) {
+			return key, description
+		}
+	}
+
+	return "", nil
+}
+
+// checkValue returns why value fails the rule's allow, minLength and
+// pattern checks, or "".
+func (r Rule) checkValue(value any) string {
+	list, isList := value.([]any)
+	str, isString := value.(string)
+
+	if r.MinLength > 0 {
+		switch {
+		case isList && len(list) < r.MinLength:
+			return fmt.Sprintf("has %d items, at least %d required", len(list), r.MinLength)
+		case isString && utf8.RuneCountInString(str) < r.MinLength:
+			return fmt.Sprintf(
+				"is %d characters long, at least %d required", utf8.RuneCountInString(str), r.MinLength,
+			)
+		}
+	}
+
+	values := []any{value}
+	if isList {
+		values = list
+	}
+
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		if len(r.Allow) > 0 && !slices.Contains(r.Allow, s) {
+			return fmt.Sprintf("'%s' is not allowed, expected one of: %s", s, strings.Join(r.Allow, ", "))
+		}
+
+		if r.pattern != nil && !r.pattern.MatchString(s) {
+			return fmt.Sprintf("'%s' doesn't match %s", s, r.Pattern)
+		}
+	}
+
+	return ""
+}
+
+// lookup finds the values at the key path segments under node, appending
+// them to found, and the paths where a key is missing to missing.
+func lookup(node any, prefix string, segments []string, found *[]match, missing *[]string) {
+	if len(segments) == 0 {
+		*found = append(*found, match{path: prefix, value: node})
+
+		return
+	}
+
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "." + key
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		if segment == "*" {
+			keys := make([]string, 0, len(n))
+			for key := range n {
+				keys = append(keys, key)
+			}
+
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				lookup(n[key], join(key), rest, found, missing)
+			}
+
+			return
+		}
+
+		child, ok := n[segment]
+		if !ok {
+			*missing = append(*missing, join(strings.Join(segments, ".")))
+
+			return
+		}
+
+		lookup(child, join(segment), rest, found, missing)
+	case []any:
+		if segment == "*" {
+			for i, item := range n {
+				lookup(item, join(strconv.Itoa(i)), rest, found, missing)
+			}
+
+			return
+		}
+
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= len(n) {
+			*missing = append(*missing, join(strings.Join(segments, ".")))
+
+			return
+		}
+
+		lookup(n[i], join(segment), rest, found, missing)
+	default:
+		*missing = append(*missing, join(strings.Join(segments, ".")))
+	}
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
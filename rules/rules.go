@@ -0,0 +1,221 @@
+// Package rules implements custom checks on publiccode.yml files, declared
+// in a rules file and assigned to catalogs and publishers, on top of the
+// validation of the publiccode.yml Standard.
+//
+// A rules file groups the rules in named rulesets and assigns them by ID:
+//
+//	rulesets:
+//	  italian-pa:
+//	    - id: italian-extension
+//	      key: it
+//	      required: true
+//	    - id: license-allowlist
+//	      key: legal.license
+//	      allow: [AGPL-3.0-or-later, EUPL-1.2]
+//	    - id: long-description
+//	      key: description.*.longDescription
+//	      minLength: 500
+//	      severity: warning
+//	  south-tyrol:
+//	    - id: languages
+//	      languages:
+//	        required: [it, de]
+//	        recommended: [en]
+//	        fields: [shortDescription, longDescription]
+//	catalogs:
+//	  CATALOG_ID: [italian-pa, south-tyrol]
+//	publishers:
+//	  PUBLISHER_ID: [italian-pa]
+//
+// Keys are paths in publiccode.yml, "*" matching any key or list item.
+// Rules check that the key is present and not empty (required), that its
+// values are in a list (allow), that strings and lists have a minimum length
+// (minLength) or that strings match a regular expression (pattern). Rules
+// with languages check the languages of description instead.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/italia/publiccode-crawler/v4/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a check on a publiccode.yml key. Failures are errors unless
+// Severity is warning.
+type Rule struct {
+	ID       string              `yaml:"id"`
+	Key      string              `yaml:"key"`
+	Severity validation.Severity `yaml:"severity,omitempty"`
+	// Message replaces the message describing the failure.
+	Message string `yaml:"message,omitempty"`
+
+	Required  bool     `yaml:"required,omitempty"`
+	Allow     []string `yaml:"allow,omitempty"`
+	MinLength int      `yaml:"minLength,omitempty"`
+	Pattern   string   `yaml:"pattern,omitempty"`
+	// Languages checks the description languages instead of Key.
+	Languages *Languages `yaml:"languages,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Languages are the languages a description must be in. A missing Required
+// language fails with the rule's severity, a missing Recommended one is a
+// warning. Fields are the description keys each of them must have, eg.
+// shortDescription. A language matches its regional variants: "en" is
+// satisfied by "en-GB".
+type Languages struct {
+	Required    []string `yaml:"required,omitempty"`
+	Recommended []string `yaml:"recommended,omitempty"`
+	Fields      []string `yaml:"fields,omitempty"`
+}
+
+// Rules are the rulesets of a rules file and their assignments. A nil
+// *Rules has no rules.
+type Rules struct {
+	Rulesets   map[string][]Rule   `yaml:"rulesets"`
+	Catalogs   map[string][]string `yaml:"catalogs"`
+	Publishers map[string][]string `yaml:"publishers"`
+}
+
+// Load loads the rules file at path.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error in reading `%s': %w", path, err)
+	}
+
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error in parsing `%s': %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// Parse parses and checks a rules file. Unknown keys are errors, so that a
+// misspelled check isn't silently skipped.
+func Parse(data []byte) (*Rules, error) {
+	var rules Rules
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var errs []error
+
+	for name, ruleset := range rules.Rulesets {
+		for i := range ruleset {
+			if err := ruleset[i].compile(); err != nil {
+				errs = append(errs, fmt.Errorf("ruleset %s: %w", name, err))
+			}
+		}
+	}
+
+	for kind, assignments := range map[string]map[string][]string{
+		"catalog": rules.Catalogs, "publisher": rules.Publishers,
+	} {
+		for id, names := range assignments {
+			for _, name := range names {
+				if _, ok := rules.Rulesets[name]; !ok {
+					errs = append(errs, fmt.Errorf("%s %s: unknown ruleset %q", kind, id, name))
+				}
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule on %q: missing id", r.Key)
+	}
+
+	if r.Languages != nil {
+		if len(r.Languages.Required) == 0 && len(r.Languages.Recommended) == 0 {
+			return fmt.Errorf("rule %s: languages without required or recommended languages", r.ID)
+		}
+
+		if r.Key != "" && r.Key != "description" {
+			return fmt.Errorf("rule %s: languages only apply to description, not %s", r.ID, r.Key)
+		}
+
+		r.Key = "description"
+	}
+
+	if r.Key == "" {
+		return fmt.Errorf("rule %s: missing key", r.ID)
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = validation.SeverityError
+	case validation.SeverityError, validation.SeverityWarning:
+	default:
+		return fmt.Errorf("rule %s: invalid severity %q, expected error or warning", r.ID, r.Severity)
+	}
+
+	if !r.checksKey() && r.Languages == nil {
+		return fmt.Errorf("rule %s: nothing to check, set required, allow, minLength, pattern or languages", r.ID)
+	}
+
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %s: invalid pattern: %w", r.ID, err)
+		}
+
+		r.pattern = pattern
+	}
+
+	return nil
+}
+
+// checksKey reports whether r has checks on the values at Key.
+func (r *Rule) checksKey() bool {
+	return r.Required || len(r.Allow) > 0 || r.MinLength > 0 || r.Pattern != ""
+}
+
+// For returns the rules assigned to the catalog and to the publisher, in
+// the order of the rulesets, each ruleset once.
+func (r *Rules) For(catalogID, publisherID string) []Rule {
+	if r == nil {
+		return nil
+	}
+
+	var names []string
+	if catalogID != "" {
+		names = append(names, r.Catalogs[catalogID]...)
+	}
+
+	if publisherID != "" {
+		names = append(names, r.Publishers[publisherID]...)
+	}
+
+	var rules []Rule
+
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			rules = append(rules, r.Rulesets[name]...)
+		}
+	}
+
+	return rules
+}
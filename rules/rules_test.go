@@ -0,0 +1,181 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/rules"
+	"github.com/italia/publiccode-crawler/v4/validation"
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rulesFile = `
+rulesets:
+  italian-pa:
+    - id: italian-extension
+      key: it
+      required: true
+    - id: license-allowlist
+      key: legal.license
+      allow: [AGPL-3.0-or-later, EUPL-1.2]
+    - id: contacts
+      key: maintenance.contacts
+      required: true
+      message: add a contact
+  descriptions:
+    - id: long-description
+      key: description.*.longDescription
+      minLength: 20
+      severity: warning
+    - id: languages
+      key: localisation.availableLanguages
+      pattern: "^[a-z]{2}$"
+catalogs:
+  cat: [italian-pa, descriptions]
+publishers:
+  pub: [descriptions]
+`
+
+func TestParse(t *testing.T) {
+	r, err := rules.Parse([]byte(rulesFile))
+	require.NoError(t, err)
+
+	ids := func(rs []rules.Rule) []string {
+		var ids []string
+		for _, rule := range rs {
+			ids = append(ids, rule.ID)
+		}
+
+		return ids
+	}
+
+	assert.Equal(t,
+		[]string{"italian-extension", "license-allowlist", "contacts", "long-description", "languages"},
+		ids(r.For("cat", "pub")),
+	)
+	assert.Equal(t, []string{"long-description", "languages"}, ids(r.For("", "pub")))
+	assert.Empty(t, r.For("other", ""))
+
+	var none *rules.Rules
+	assert.Empty(t, none.For("cat", "pub"))
+}
+
+func TestParse_invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing id":       "rulesets: {a: [{key: name, required: true}]}",
+		"missing key":      "rulesets: {a: [{id: r, required: true}]}",
+		"no check":         "rulesets: {a: [{id: r, key: name}]}",
+		"invalid severity": "rulesets: {a: [{id: r, key: name, required: true, severity: fatal}]}",
+		"invalid pattern":  "rulesets: {a: [{id: r, key: name, pattern: '('}]}",
+		"unknown ruleset":  "catalogs: {cat: [missing]}",
+		"unknown key":      "rulesets: {a: [{id: r, key: name, required: true, alow: [x]}]}",
+		"unknown section":  "ruleset: {a: [{id: r, key: name, required: true}]}",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := rules.Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// A misspelled check next to a valid one would otherwise be skipped.
+func TestParse_unknownRuleKey(t *testing.T) {
+	_, err := rules.Parse([]byte("rulesets: {a: [{id: r, key: name, required: true, minlength: 10}]}"))
+	require.ErrorContains(t, err, "minlength")
+
+	r, err := rules.Parse(nil)
+	require.NoError(t, err)
+	assert.Empty(t, r.For("cat", "pub"))
+}
+
+func TestCheck(t *testing.T) {
+	r, err := rules.Parse([]byte(rulesFile))
+	require.NoError(t, err)
+
+	var parsed publiccode.PublicCodeV0
+	parsed.Name = "app"
+	parsed.Legal.License = "MIT"
+	parsed.Description = map[string]publiccode.DescV0{
+		"en": {LongDescription: "A long enough description."},
+		"it": {LongDescription: "Breve."},
+	}
+	parsed.Localisation.AvailableLanguages = []string{"it", "en-GB"}
+
+	entries, err := rules.Check(r.For("cat", ""), parsed)
+	require.NoError(t, err)
+
+	assert.Equal(t, []validation.Entry{
+		{Key: "it", Severity: validation.SeverityError, Message: "is required", RuleID: "italian-extension"},
+		{
+			Key: "legal.license", Severity: validation.SeverityError,
+			Message: "'MIT' is not allowed, expected one of: AGPL-3.0-or-later, EUPL-1.2", RuleID: "license-allowlist",
+		},
+		{Key: "maintenance.contacts", Severity: validation.SeverityError, Message: "add a contact", RuleID: "contacts"},
+		{
+			Key: "description.it.longDescription", Severity: validation.SeverityWarning,
+			Message: "is 6 characters long, at least 20 required", RuleID: "long-description",
+		},
+		{
+			Key: "localisation.availableLanguages", Severity: validation.SeverityError,
+			Message: "'en-GB' doesn't match ^[a-z]{2}$", RuleID: "languages",
+		},
+	}, entries)
+
+	parsed.IT = &publiccode.ITSectionV0{}
+	parsed.Legal.License = "EUPL-1.2"
+	contacts := []publiccode.ContactV0{{Name: "Maintainer"}}
+	parsed.Maintenance.Contacts = &contacts
+	parsed.Description["it"] = publiccode.DescV0{LongDescription: "Una descrizione abbastanza lunga."}
+	parsed.Localisation.AvailableLanguages = []string{"it", "en"}
+
+	entries, err = rules.Check(r.For("cat", ""), parsed)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCheck_languages(t *testing.T) {
+	r, err := rules.Parse([]byte(`
+rulesets:
+  south-tyrol:
+    - id: languages
+      languages:
+        required: [it, de]
+        recommended: [en, fr]
+        fields: [shortDescription, features]
+catalogs:
+  cat: [south-tyrol]
+`))
+	require.NoError(t, err)
+
+	var parsed publiccode.PublicCodeV0
+	parsed.Description = map[string]publiccode.DescV0{
+		"it":    {ShortDescription: "Applicazione", Features: &[]string{"Una"}},
+		"en-GB": {ShortDescription: "Application"},
+	}
+
+	entries, err := rules.Check(r.For("cat", ""), parsed)
+	require.NoError(t, err)
+
+	assert.Equal(t, []validation.Entry{
+		{Key: "description.de", Severity: validation.SeverityError, Message: "is required in de", RuleID: "languages"},
+		{
+			Key: "description.en-GB.features", Severity: validation.SeverityWarning,
+			Message: "is recommended in en-GB", RuleID: "languages",
+		},
+		{
+			Key: "description.fr", Severity: validation.SeverityWarning,
+			Message: "is recommended in fr", RuleID: "languages",
+		},
+	}, entries)
+}
+
+func TestParse_invalidLanguages(t *testing.T) {
+	_, err := rules.Parse([]byte("rulesets: {a: [{id: r, languages: {fields: [features]}}]}"))
+	assert.Error(t, err)
+
+	_, err = rules.Parse([]byte("rulesets: {a: [{id: r, key: name, languages: {required: [it]}}]}"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,288 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/internal"
+	log "github.com/sirupsen/logrus"
+)
+
+const azureDevOpsAPIVersion = "7.1"
+
+// AzureDevOpsScanner scans Azure DevOps Repos, both on dev.azure.com and on
+// the legacy {org}.visualstudio.com hosts. Requests are authenticated with the
+// personal access token configured in CREDENTIALS for "dev.azure.com/{org}"
+// or for the host, falling back to the AZURE_DEVOPS_TOKEN environment
+// variable.
+type AzureDevOpsScanner struct {
+	filter common.RepoFilter
+}
+
+func NewAzureDevOpsScanner() AzureDevOpsScanner {
+	return AzureDevOpsScanner{}
+}
+
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner AzureDevOpsScanner) WithFilter(filter common.RepoFilter) AzureDevOpsScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
+type azureDevOpsRepo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	DefaultBranch string `json:"defaultBranch"`
+	WebURL        string `json:"webUrl"`
+	IsDisabled    bool   `json:"isDisabled"`
+	Project       struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"project"`
+}
+
+type azureDevOpsRepoList struct {
+	Value []azureDevOpsRepo `json:"value"`
+}
+
+// azureDevOpsLocation is an Azure DevOps URL split into its parts.
+type azureDevOpsLocation struct {
+	// base is the organization URL (eg. https://dev.azure.com/myorg).
+	base    string
+	org     string
+	project string
+	repo    string
+}
+
+// List scans all the repositories of an Azure DevOps organization or project
+// represented by groupURL.
+func (scanner AzureDevOpsScanner) List(
+	groupURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	log.Debugf("AzureDevOpsScanner.List(%s)", groupURL.String())
+
+	loc, err := parseAzureDevOpsURL(groupURL)
+	if err != nil {
+		return err
+	}
+
+	if loc.repo != "" {
+		return fmt.Errorf("AzureDevOpsScanner: %s doesn't look like an organization or project", groupURL.String())
+	}
+
+	apiURL := loc.base + "/_apis/git/repositories?api-version=" + azureDevOpsAPIVersion
+	if loc.project != "" {
+		apiURL = loc.base + "/" + url.PathEscape(loc.project) +
+			"/_apis/git/repositories?api-version=" + azureDevOpsAPIVersion
+	}
+
+	var list azureDevOpsRepoList
+	if err := azureDevOpsGet(loc, apiURL, &list); err != nil {
+		return fmt.Errorf("AzureDevOpsScanner: %w", err)
+	}
+
+	for _, repo := range list.Value {
+		// Topics, visibility and push times aren't in the repositories API.
+		info := common.RepoInfo{Name: repo.Project.Name + "/" + repo.Name}
+
+		if !scanner.filter.Match(info) {
+			log.Debugf("AzureDevOpsScanner: skipping %s, not matching the source filters", info.Name)
+
+			continue
+		}
+
+		if err := addAzureDevOpsRepo(loc, nil, repo, publisher, repositories); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scan scans a single Azure DevOps repository represented by repoURL.
+func (scanner AzureDevOpsScanner) Scan(
+	repoURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	log.Debugf("AzureDevOpsScanner.Scan(%s)", repoURL.String())
+
+	loc, err := parseAzureDevOpsURL(repoURL)
+	if err != nil {
+		return err
+	}
+
+	if loc.repo == "" {
+		return fmt.Errorf("AzureDevOpsScanner: %s doesn't look like a repo", repoURL.String())
+	}
+
+	apiURL := fmt.Sprintf("%s/%s/_apis/git/repositories/%s?api-version=%s",
+		loc.base, url.PathEscape(loc.project), url.PathEscape(loc.repo), azureDevOpsAPIVersion)
+
+	var repo azureDevOpsRepo
+	if err := azureDevOpsGet(loc, apiURL, &repo); err != nil {
+		return fmt.Errorf("AzureDevOpsScanner: %w", err)
+	}
+
+	return addAzureDevOpsRepo(loc, &repoURL, repo, publisher, repositories)
+}
+
+func addAzureDevOpsRepo(
+	loc azureDevOpsLocation, originalURL *url.URL, repo azureDevOpsRepo,
+	publisher common.Publisher, repositories chan common.Repository,
+) error {
+	if repo.IsDisabled {
+		log.Debugf("AzureDevOpsScanner: skipping disabled repo %s/%s", repo.Project.Name, repo.Name)
+
+		return nil
+	}
+
+	// Empty repos have no default branch.
+	if repo.DefaultBranch == "" {
+		return nil
+	}
+
+	branch := strings.TrimPrefix(repo.DefaultBranch, "refs/heads/")
+
+	canonicalURL, err := url.Parse(repo.WebURL)
+	if err != nil {
+		return fmt.Errorf("AzureDevOpsScanner: failed to get canonical repo URL for %s: %w", repo.Name, err)
+	}
+
+	if originalURL == nil {
+		originalURL = canonicalURL
+	}
+
+	query := url.Values{}
+	query.Set("path", "/publiccode.yml")
+	query.Set("versionDescriptor.version", branch)
+	query.Set("versionDescriptor.versionType", "branch")
+	query.Set("$format", "octetStream")
+	query.Set("api-version", azureDevOpsAPIVersion)
+
+	headers := map[string]string{}
+	if auth := azureDevOpsAuthorization(loc); auth != "" {
+		headers["Authorization"] = auth
+	}
+
+	// The file path is in the query of the items API, so relative paths in
+	// publiccode.yml can't be resolved against FileRawURL.
+	repositories <- common.Repository{
+		Name:         repo.Project.Name + "/" + repo.Name,
+		FileRawURL:   loc.base + "/_apis/git/repositories/" + url.PathEscape(repo.ID) + "/items?" + query.Encode(),
+		URL:          *originalURL,
+		CanonicalURL: *canonicalURL,
+		GitBranch:    branch,
+		Publisher:    publisher,
+		Headers:      headers,
+		NoBaseURL:    true,
+	}
+
+	return nil
+}
+
+// parseAzureDevOpsURL splits u, in one of these forms:
+//
+//	https://dev.azure.com/{org}[/{project}[/_git/{repo}]]
+//	https://dev.azure.com/{org}/_git/{repo}
+//	https://{org}.visualstudio.com[/{project}[/_git/{repo}]]
+//
+// The second one is the shorthand for a repo named as its project.
+func parseAzureDevOpsURL(u url.URL) (azureDevOpsLocation, error) {
+	var loc azureDevOpsLocation
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if segments[0] == "" {
+		segments = nil
+	}
+
+	if org, ok := strings.CutSuffix(strings.ToLower(u.Hostname()), ".visualstudio.com"); ok {
+		loc.org = org
+		loc.base = u.Scheme + "://" + u.Host
+	} else {
+		if len(segments) == 0 {
+			return loc, fmt.Errorf("AzureDevOpsScanner: missing organization in %s", u.String())
+		}
+
+		loc.org = segments[0]
+		loc.base = u.Scheme + "://" + u.Host + "/" + url.PathEscape(loc.org)
+		segments = segments[1:]
+	}
+
+	switch {
+	case len(segments) == 0:
+	case len(segments) == 1:
+		loc.project = segments[0]
+	case len(segments) == 2 && segments[0] == "_git":
+		loc.project = segments[1]
+		loc.repo = segments[1]
+	case len(segments) == 3 && segments[1] == "_git":
+		loc.project = segments[0]
+		loc.repo = segments[2]
+	default:
+		return loc, fmt.Errorf("AzureDevOpsScanner: unsupported URL %s", u.String())
+	}
+
+	loc.repo = strings.TrimSuffix(loc.repo, ".git")
+
+	return loc, nil
+}
+
+func azureDevOpsGet(loc azureDevOpsLocation, apiURL string, out any) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	if auth := azureDevOpsAuthorization(loc); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", apiURL, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s: %w", apiURL, err)
+	}
+
+	return nil
+}
+
+// azureDevOpsAuthorization returns the Authorization header value for the
+// organization in loc, or an empty string if there's no token for it.
+func azureDevOpsAuthorization(loc azureDevOpsLocation) string {
+	baseURL, err := url.Parse(loc.base)
+	if err != nil {
+		return ""
+	}
+
+	token := internal.Credential(baseURL.Host + "/" + loc.org)
+	if token == "" {
+		token = internal.Credential(baseURL.Host)
+	}
+
+	if token == "" {
+		token = os.Getenv("AZURE_DEVOPS_TOKEN")
+	}
+
+	if token == "" {
+		return ""
+	}
+
+	// Personal access tokens go in the password field, with an empty user.
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+token))
+}
@@ -0,0 +1,193 @@
+package scanner_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func azureDevOpsRepoJSON(id, project, name, defaultBranch string, disabled bool) map[string]any {
+	return map[string]any{
+		"id":            id,
+		"name":          name,
+		"defaultBranch": defaultBranch,
+		"webUrl":        "https://dev.azure.com/myorg/" + project + "/_git/" + name,
+		"isDisabled":    disabled,
+		"project":       map[string]any{"id": project + "-id", "name": project},
+	}
+}
+
+func TestAzureDevOpsScanner_Scan_success(t *testing.T) {
+	repo := azureDevOpsRepoJSON("b1e4", "myproject", "myrepo", "refs/heads/main", false)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/myorg/myproject/_apis/git/repositories/myrepo", r.URL.Path)
+		assert.Equal(t, "7.1", r.URL.Query().Get("api-version"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(repo)
+	}))
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/myorg/myproject/_git/myrepo")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewAzureDevOpsScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	got := <-repositories
+	assert.Equal(t, "myproject/myrepo", got.Name)
+	assert.Equal(t, "main", got.GitBranch)
+	assert.Equal(t, *repoURL, got.URL)
+	assert.Equal(t, "https://dev.azure.com/myorg/myproject/_git/myrepo", got.CanonicalURL.String())
+
+	rawURL, err := url.Parse(got.FileRawURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/myorg/_apis/git/repositories/b1e4/items", rawURL.Path)
+	assert.Equal(t, "/publiccode.yml", rawURL.Query().Get("path"))
+	assert.Equal(t, "main", rawURL.Query().Get("versionDescriptor.version"))
+	assert.Equal(t, "branch", rawURL.Query().Get("versionDescriptor.versionType"))
+}
+
+func TestAzureDevOpsScanner_Scan_projectShorthand(t *testing.T) {
+	repo := azureDevOpsRepoJSON("b1e4", "myrepo", "myrepo", "refs/heads/main", false)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/myorg/myrepo/_apis/git/repositories/myrepo", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(repo)
+	}))
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/myorg/_git/myrepo")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewAzureDevOpsScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	assert.Len(t, repositories, 1)
+}
+
+func TestAzureDevOpsScanner_Scan_personalAccessToken(t *testing.T) {
+	repo := azureDevOpsRepoJSON("b1e4", "myproject", "myrepo", "refs/heads/main", false)
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(":my-pat"))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, wantAuth, r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(repo)
+	}))
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/MyOrg/myproject/_git/myrepo")
+	require.NoError(t, err)
+
+	viper.Set("CREDENTIALS", map[string]string{repoURL.Host + "/myorg": "my-pat"})
+	t.Cleanup(func() { viper.Set("CREDENTIALS", nil) })
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewAzureDevOpsScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	// The raw file is fetched with the same credentials.
+	assert.Equal(t, wantAuth, (<-repositories).Headers["Authorization"])
+}
+
+func TestAzureDevOpsScanner_Scan_apiError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/myorg/myproject/_git/myrepo")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewAzureDevOpsScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.Error(t, err)
+	assert.Empty(t, repositories)
+}
+
+func TestAzureDevOpsScanner_Scan_notARepo(t *testing.T) {
+	repoURL, _ := url.Parse("https://dev.azure.com/myorg/myproject")
+
+	repositories := make(chan common.Repository, 1)
+
+	err := scanner.NewAzureDevOpsScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.Error(t, err)
+}
+
+func TestAzureDevOpsScanner_List_project(t *testing.T) {
+	repos := []map[string]any{
+		azureDevOpsRepoJSON("1", "myproject", "enabled", "refs/heads/main", false),
+		azureDevOpsRepoJSON("2", "myproject", "disabled", "refs/heads/main", true),
+		azureDevOpsRepoJSON("3", "myproject", "empty", "", false),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/myorg/myproject/_apis/git/repositories", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"value": repos, "count": len(repos)})
+	}))
+	defer ts.Close()
+
+	groupURL, err := url.Parse(ts.URL + "/myorg/myproject")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	err = scanner.NewAzureDevOpsScanner().List(*groupURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+	assert.Equal(t, "myproject/enabled", (<-repositories).Name)
+}
+
+func TestAzureDevOpsScanner_List_organization(t *testing.T) {
+	repos := []map[string]any{
+		azureDevOpsRepoJSON("1", "project1", "repo1", "refs/heads/main", false),
+		azureDevOpsRepoJSON("2", "project2", "repo2", "refs/heads/develop", false),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/myorg/_apis/git/repositories", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"value": repos, "count": len(repos)})
+	}))
+	defer ts.Close()
+
+	groupURL, err := url.Parse(ts.URL + "/myorg")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	err = scanner.NewAzureDevOpsScanner().List(*groupURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	assert.Len(t, repositories, 2)
+}
@@ -12,6 +12,7 @@ import (
 
 type BitBucketScanner struct {
 	client *bitbucket.Client
+	filter common.RepoFilter
 }
 
 func NewBitBucketScanner() BitBucketScanner {
@@ -23,6 +24,14 @@ func NewBitBucketScanner() BitBucketScanner {
 	return BitBucketScanner{client: client}
 }
 
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner BitBucketScanner) WithFilter(filter common.RepoFilter) BitBucketScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
 // List scans a Bitbucket workspace represented by url.
 func (scanner BitBucketScanner) List(
 	url url.URL, publisher common.Publisher, repositories chan common.Repository,
@@ -53,6 +62,12 @@ func (scanner BitBucketScanner) List(
 			continue
 		}
 
+		if !scanner.filter.Match(bitBucketRepoInfo(item)) {
+			log.Debugf("BitBucketScanner: skipping %s, not matching the source filters", item.Full_name)
+
+			continue
+		}
+
 		opt := &bitbucket.RepositoryFilesOptions{
 			Owner:    owner,
 			RepoSlug: item.Slug,
@@ -145,3 +160,17 @@ func (scanner BitBucketScanner) Scan(
 
 	return nil
 }
+
+func bitBucketRepoInfo(repo bitbucket.Repository) common.RepoInfo {
+	info := common.RepoInfo{Name: repo.Full_name, Visibility: "public"}
+
+	if repo.Is_private {
+		info.Visibility = "private"
+	}
+
+	if repo.UpdatedOnTime != nil {
+		info.PushedAt = *repo.UpdatedOnTime
+	}
+
+	return info
+}
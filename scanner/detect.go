@@ -0,0 +1,268 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// forgeCacheTTL is how long a detection result is trusted before probing the
+// host again.
+const forgeCacheTTL = 30 * 24 * time.Hour
+
+// DetectedForge is the result of probing a host for a known forge.
+type DetectedForge struct {
+	// Forge is the software detected on the host ("gitlab", "gitea",
+	// "forgejo", "bitbucket-server"), empty if none was recognized.
+	Forge string `json:"forge"`
+	// Driver is the crawler driver to use for the host, empty if there's none
+	// supporting Forge.
+	Driver     string    `json:"driver"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// ForgeDetector detects which forge runs on the hosts common.InferVCSDriver
+// doesn't recognize, by probing their well-known API endpoints. Results are
+// cached per host in a JSON file. It's safe for concurrent use: hosts are
+// probed once at a time, without blocking the detection of other hosts.
+type ForgeDetector struct {
+	client    *http.Client
+	cachePath string
+
+	mu    sync.Mutex
+	cache map[string]DetectedForge
+	// probing holds the probes in flight, by host.
+	probing map[string]*forgeProbe
+	// detected holds the hosts resolved to a driver during this run.
+	detected map[string]DetectedForge
+	// unsupported holds the hosts running a forge no driver supports, warned
+	// about during this run.
+	unsupported map[string]bool
+}
+
+// forgeProbe is a probe in flight, done closed when result and err are set.
+type forgeProbe struct {
+	done   chan struct{}
+	result DetectedForge
+	err    error
+}
+
+// NewForgeDetector returns a ForgeDetector caching its results in cachePath.
+// A missing or unreadable cache file just starts an empty cache.
+func NewForgeDetector(cachePath string) *ForgeDetector {
+	detector := &ForgeDetector{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		cachePath:   cachePath,
+		cache:       map[string]DetectedForge{},
+		probing:     map[string]*forgeProbe{},
+		detected:    map[string]DetectedForge{},
+		unsupported: map[string]bool{},
+	}
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		if err := json.Unmarshal(data, &detector.cache); err != nil {
+			log.Warnf("ignoring invalid forge detection cache %s: %s", cachePath, err.Error())
+		}
+	}
+
+	return detector
+}
+
+// Detect returns the driver for the host of u, or an empty string if the host
+// doesn't run a forge with a supported driver. Hosts that can't be reached
+// return an error and are probed again at the next Detect.
+func (d *ForgeDetector) Detect(u url.URL) (string, error) {
+	host := u.Host
+
+	d.mu.Lock()
+
+	result, ok := d.cache[host]
+	if !ok || time.Since(result.DetectedAt) > forgeCacheTTL {
+		probe, inFlight := d.probing[host]
+		if !inFlight {
+			probe = &forgeProbe{done: make(chan struct{})}
+			d.probing[host] = probe
+		}
+
+		d.mu.Unlock()
+
+		if inFlight {
+			<-probe.done
+		} else {
+			d.runProbe(host, u, probe)
+		}
+
+		if probe.err != nil {
+			return "", probe.err
+		}
+
+		result = probe.result
+
+		d.mu.Lock()
+	}
+
+	defer d.mu.Unlock()
+
+	switch {
+	case result.Driver != "":
+		if _, seen := d.detected[host]; !seen {
+			log.Infof("[%s] detected %s, using the %q driver", host, result.Forge, result.Driver)
+			d.detected[host] = result
+		}
+	case result.Forge != "":
+		if !d.unsupported[host] {
+			log.Warnf(
+				"[%s] detected %s, which no driver supports: set driver: git for its single repositories",
+				host, result.Forge,
+			)
+			d.unsupported[host] = true
+		}
+	}
+
+	return result.Driver, nil
+}
+
+// runProbe probes host and sets the outcome in probe, caching it if it's
+// definitive. A cache that can't be saved is only a warning.
+func (d *ForgeDetector) runProbe(host string, u url.URL, probe *forgeProbe) {
+	defer close(probe.done)
+
+	probe.result, probe.err = d.probe(u)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.probing, host)
+
+	if probe.err != nil {
+		return
+	}
+
+	probe.result.DetectedAt = time.Now().UTC()
+	d.cache[host] = probe.result
+
+	// The result is cached in memory anyway: not being able to write
+	// DATADIR only means probing again in the next run.
+	if err := d.save(); err != nil {
+		log.Warnf("[%s] %s", host, err.Error())
+	}
+}
+
+// Detected returns the hosts resolved to a driver so far, for reports.
+func (d *ForgeDetector) Detected() map[string]DetectedForge {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return maps.Clone(d.detected)
+}
+
+// probe returns the forge detected on the host of u. Not recognizing any is
+// only definitive if every probe got an HTTP reply: otherwise it returns an
+// error, so that a host that's down for a while isn't cached as unsupported.
+func (d *ForgeDetector) probe(u url.URL) (DetectedForge, error) {
+	base := u.Scheme + "://" + u.Host
+
+	var errs []error
+
+	getJSON := func(apiURL string, out any) int {
+		status, err := d.getJSON(apiURL, out)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		return status
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+
+	if status := getJSON(base+"/api/v1/version", &version); status == http.StatusOK && version.Version != "" {
+		status := getJSON(base+"/api/forgejo/v1/version", &version)
+
+		switch {
+		case status == http.StatusOK:
+			return DetectedForge{Forge: "forgejo", Driver: "forgejo"}, nil
+		case len(errs) == 0:
+			return DetectedForge{Forge: "gitea", Driver: "gitea"}, nil
+		}
+	}
+
+	// GitLab answers 401 to anonymous requests, unless the instance
+	// is configured otherwise.
+	var gitlab struct {
+		Version string `json:"version"`
+		Message string `json:"message"`
+	}
+
+	status := getJSON(base+"/api/v4/version", &gitlab)
+	if (status == http.StatusOK && gitlab.Version != "") || (status == http.StatusUnauthorized && gitlab.Message != "") {
+		return DetectedForge{Forge: "gitlab", Driver: "gitlab"}, nil
+	}
+
+	var bitbucket struct {
+		DisplayName string `json:"displayName"`
+	}
+
+	status = getJSON(base+"/rest/api/1.0/application-properties", &bitbucket)
+	if status == http.StatusOK && bitbucket.DisplayName == "Bitbucket" {
+		return DetectedForge{Forge: "bitbucket-server"}, nil
+	}
+
+	if len(errs) > 0 {
+		return DetectedForge{}, fmt.Errorf("can't detect the forge on %s: %w", u.Host, errors.Join(errs...))
+	}
+
+	return DetectedForge{}, nil
+}
+
+// getJSON GETs apiURL decoding the JSON response body into out, and returns
+// the HTTP status code. A body that isn't JSON leaves out untouched. The
+// error is set if there was no HTTP reply.
+func (d *ForgeDetector) getJSON(apiURL string, out any) (int, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Debugf("ForgeDetector: GET %s: %s", apiURL, err.Error())
+
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_ = json.NewDecoder(resp.Body).Decode(out)
+
+	return resp.StatusCode, nil
+}
+
+func (d *ForgeDetector) save() error {
+	data, err := json.MarshalIndent(d.cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode forge detection cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.cachePath), 0o755); err != nil {
+		return fmt.Errorf("can't save forge detection cache: %w", err)
+	}
+
+	if err := os.WriteFile(d.cachePath, data, 0o600); err != nil {
+		return fmt.Errorf("can't save forge detection cache: %w", err)
+	}
+
+	return nil
+}
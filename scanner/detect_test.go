@@ -0,0 +1,233 @@
+package scanner_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newForgeTestServer returns a test server answering the given paths with
+// the given status and JSON body, and 404 to anything else.
+func newForgeTestServer(t *testing.T, routes map[string]func() (int, any)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("<html>not found</html>"))
+
+			return
+		}
+
+		status, body := route()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+func detect(t *testing.T, detector *scanner.ForgeDetector, rawURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	driver, err := detector.Detect(*u)
+	require.NoError(t, err)
+
+	return driver
+}
+
+func TestForgeDetector_Detect(t *testing.T) {
+	version := func() (int, any) { return http.StatusOK, map[string]any{"version": "1.22.0"} }
+
+	tests := []struct {
+		name   string
+		routes map[string]func() (int, any)
+		forge  string
+		driver string
+	}{
+		{
+			name:   "gitea",
+			routes: map[string]func() (int, any){"/api/v1/version": version},
+			forge:  "gitea",
+			driver: "gitea",
+		},
+		{
+			name: "forgejo",
+			routes: map[string]func() (int, any){
+				"/api/v1/version":         version,
+				"/api/forgejo/v1/version": version,
+			},
+			forge:  "forgejo",
+			driver: "forgejo",
+		},
+		{
+			name: "gitlab",
+			routes: map[string]func() (int, any){"/api/v4/version": func() (int, any) {
+				return http.StatusUnauthorized, map[string]any{"message": "401 Unauthorized"}
+			}},
+			forge:  "gitlab",
+			driver: "gitlab",
+		},
+		{
+			name: "bitbucket server",
+			routes: map[string]func() (int, any){"/rest/api/1.0/application-properties": func() (int, any) {
+				return http.StatusOK, map[string]any{"version": "8.9.0", "displayName": "Bitbucket"}
+			}},
+			forge:  "bitbucket-server",
+			driver: "",
+		},
+		{
+			name:   "unknown",
+			routes: map[string]func() (int, any){},
+			forge:  "",
+			driver: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, _ := newForgeTestServer(t, tc.routes)
+
+			detector := scanner.NewForgeDetector(filepath.Join(t.TempDir(), "forges.json"))
+
+			assert.Equal(t, tc.driver, detect(t, detector, ts.URL+"/org"))
+
+			if tc.driver != "" {
+				host := ts.Listener.Addr().String()
+				assert.Equal(t, tc.forge, detector.Detected()[host].Forge)
+			} else {
+				assert.Empty(t, detector.Detected())
+			}
+		})
+	}
+}
+
+func TestForgeDetector_Detect_cached(t *testing.T) {
+	ts, requests := newForgeTestServer(t, map[string]func() (int, any){
+		"/api/v1/version": func() (int, any) { return http.StatusOK, map[string]any{"version": "1.22.0"} },
+	})
+
+	cachePath := filepath.Join(t.TempDir(), "forges.json")
+
+	assert.Equal(t, "gitea", detect(t, scanner.NewForgeDetector(cachePath), ts.URL+"/org"))
+
+	probes := requests.Load()
+
+	// A new detector, as in the next run, reads the result from the cache
+	// in DATADIR instead of probing the host again.
+	detector := scanner.NewForgeDetector(cachePath)
+
+	assert.Equal(t, "gitea", detect(t, detector, ts.URL+"/another-org"))
+	assert.Equal(t, probes, requests.Load())
+	assert.Len(t, detector.Detected(), 1)
+}
+
+func TestForgeDetector_Detect_unreachable(t *testing.T) {
+	ts, _ := newForgeTestServer(t, map[string]func() (int, any){
+		"/api/v1/version": func() (int, any) { return http.StatusOK, map[string]any{"version": "1.22.0"} },
+	})
+
+	// Grab a free address, then stop listening on it.
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "forges.json")
+	detector := scanner.NewForgeDetector(cachePath)
+
+	u, err := url.Parse(downURL + "/org")
+	require.NoError(t, err)
+
+	_, err = detector.Detect(*u)
+	require.Error(t, err)
+
+	// The failure isn't cached: once the host is back it's detected.
+	up, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	u.Host = up.Host
+	assert.Equal(t, "gitea", detect(t, detector, u.String()))
+
+	data, err := os.ReadFile(cachePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "\""+strings.TrimPrefix(downURL, "http://")+"\"")
+}
+
+func TestForgeDetector_Detect_unwritableCache(t *testing.T) {
+	ts, _ := newForgeTestServer(t, map[string]func() (int, any){
+		"/api/v1/version": func() (int, any) { return http.StatusOK, map[string]any{"version": "1.22.0"} },
+	})
+
+	// The cache directory can't be created, as its parent is a file.
+	parent := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(parent, nil, 0o600))
+
+	detector := scanner.NewForgeDetector(filepath.Join(parent, "forges.json"))
+
+	assert.Equal(t, "gitea", detect(t, detector, ts.URL+"/org"))
+	assert.Len(t, detector.Detected(), 1)
+}
+
+func TestForgeDetector_Detect_concurrent(t *testing.T) {
+	release := make(chan struct{})
+
+	slow, slowRequests := newForgeTestServer(t, map[string]func() (int, any){
+		"/api/v1/version": func() (int, any) {
+			<-release
+
+			return http.StatusOK, map[string]any{"version": "1.22.0"}
+		},
+	})
+	fast, _ := newForgeTestServer(t, map[string]func() (int, any){
+		"/api/v4/version": func() (int, any) { return http.StatusOK, map[string]any{"version": "17.0.0"} },
+	})
+
+	detector := scanner.NewForgeDetector(filepath.Join(t.TempDir(), "forges.json"))
+
+	slowURL, err := url.Parse(slow.URL + "/org")
+	require.NoError(t, err)
+
+	drivers := make(chan string, 3)
+	for range 3 {
+		go func() {
+			driver, _ := detector.Detect(*slowURL)
+			drivers <- driver
+		}()
+	}
+
+	// Other hosts aren't blocked by the slow probe.
+	assert.Eventually(t, func() bool { return slowRequests.Load() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "gitlab", detect(t, detector, fast.URL+"/org"))
+
+	close(release)
+
+	for range 3 {
+		assert.Equal(t, "gitea", <-drivers)
+	}
+
+	// The slow host was probed once for all the callers: the version, then
+	// the Forgejo one.
+	assert.EqualValues(t, 2, slowRequests.Load())
+}
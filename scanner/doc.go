@@ -1,4 +1,5 @@
 // Package scanner provides Scanner implementations for VCS hosts (GitHub,
-// GitLab, Bitbucket, Gitea/Forgejo) used by the crawler to scan a single
-// repository for a publiccode.yml file.
+// GitLab, Bitbucket, Gitea/Forgejo, SourceHut, Azure DevOps and plain git
+// servers) used by the crawler to scan a single repository for a
+// publiccode.yml file.
 package scanner
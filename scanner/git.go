@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/internal"
+	log "github.com/sirupsen/logrus"
+)
+
+// GitScanner scans plain git repositories served over HTTP(S), for hosts
+// with no supported API (cgit, gitweb, Gerrit, bare git servers, ...).
+// Since there's no way to list repositories without an API, it's a Scanner
+// only.
+type GitScanner struct{}
+
+func NewGitScanner() GitScanner {
+	return GitScanner{}
+}
+
+// Scan finds the default branch of the repository represented by repoURL
+// with ls-remote and reads publiccode.yml out of a shallow, blob-filtered
+// fetch of that branch. The emitted repository carries the file content, so
+// it doesn't have a FileRawURL.
+func (scanner GitScanner) Scan(
+	repoURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	log.Debugf("GitScanner.Scan(%s)", repoURL.String())
+
+	name := strings.TrimSuffix(strings.Trim(repoURL.Path, "/"), ".git")
+	if name == "" {
+		return fmt.Errorf("GitScanner: doesn't look like a git repo %s", repoURL.String())
+	}
+
+	branch, err := internal.GitDefaultBranch(repoURL.String())
+	if err != nil {
+		return fmt.Errorf("GitScanner: %w", err)
+	}
+
+	content, err := gitReadFile(repoURL.String(), branch, "publiccode.yml")
+	if err != nil {
+		if errors.Is(err, ErrPubliccodeNotFound) {
+			return err
+		}
+
+		return fmt.Errorf("GitScanner: %w", err)
+	}
+
+	repositories <- common.Repository{
+		Name:         name,
+		URL:          repoURL,
+		CanonicalURL: repoURL,
+		FileContent:  content,
+		GitBranch:    branch,
+		Publisher:    publisher,
+	}
+
+	return nil
+}
+
+// gitReadFile returns the content of file at the tip of branch in the remote
+// repository. Only the commit and its trees are fetched at first, then git
+// lazily fetches the single blob being read.
+func gitReadFile(remote, branch, file string) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "gitscanner-*")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	_, err = internal.RunGit(
+		"", "clone", "--quiet", "--bare", "--depth=1", "--filter=blob:none",
+		"--single-branch", "--branch", branch, "--", remote, tmpDir,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := internal.RunGit(tmpDir, "ls-tree", "--name-only", "HEAD", file)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(string(out)) == "" {
+		return nil, ErrPubliccodeNotFound
+	}
+
+	return internal.RunGit(tmpDir, "cat-file", "blob", "HEAD:"+file)
+}
@@ -0,0 +1,117 @@
+package scanner_test
+
+import (
+	"io"
+	"net/http/cgi"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.CommandContext(t.Context(), "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// newGitTestServer serves, through git's smart HTTP protocol, a bare repo
+// named project.git whose default branch is trunk and contains files.
+func newGitTestServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+
+	work := t.TempDir()
+	git(t, work, "init", "--quiet", "--initial-branch=trunk")
+
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(work, name), []byte(content), 0o600))
+	}
+
+	git(t, work, "add", "--all")
+	git(t, work, "commit", "--quiet", "--allow-empty", "--message=init")
+
+	root := t.TempDir()
+	git(t, root, "clone", "--quiet", "--bare", work, "project.git")
+	git(t, filepath.Join(root, "project.git"), "config", "uploadpack.allowFilter", "true")
+
+	return httptest.NewServer(&cgi.Handler{
+		Path:   gitPath,
+		Args:   []string{"http-backend"},
+		Env:    []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+		Stderr: io.Discard,
+	})
+}
+
+func TestGitScanner_Scan_success(t *testing.T) {
+	ts := newGitTestServer(t, map[string]string{
+		"publiccode.yml": "publiccodeYmlVersion: '0'\n",
+		"README.md":      "hello",
+	})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/project.git")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewGitScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	got := <-repositories
+	assert.Equal(t, "project", got.Name)
+	assert.Equal(t, "trunk", got.GitBranch)
+	assert.Empty(t, got.FileRawURL)
+	assert.Equal(t, "publiccodeYmlVersion: '0'\n", string(got.FileContent))
+	assert.Equal(t, *repoURL, got.CanonicalURL)
+}
+
+func TestGitScanner_Scan_notFound(t *testing.T) {
+	ts := newGitTestServer(t, map[string]string{"README.md": "hello"})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/project.git")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewGitScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.ErrorIs(t, err, scanner.ErrPubliccodeNotFound)
+	assert.Empty(t, repositories)
+}
+
+func TestGitScanner_Scan_missingRepo(t *testing.T) {
+	ts := newGitTestServer(t, map[string]string{"README.md": "hello"})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/missing.git")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewGitScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, scanner.ErrPubliccodeNotFound)
+}
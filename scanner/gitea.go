@@ -8,29 +8,80 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/internal"
 	log "github.com/sirupsen/logrus"
 )
 
 var errNotFound = errors.New("not found")
 
-type GiteaScanner struct{}
+// GiteaScanner scans Gitea and Forgejo instances. Requests are authenticated
+// with the token configured for the instance's host in CREDENTIALS, falling
+// back to the GITEA_TOKEN environment variable.
+type GiteaScanner struct {
+	topics      []string
+	namePattern string
+	filter      common.RepoFilter
+}
 
 func NewGiteaScanner() GiteaScanner {
 	return GiteaScanner{}
 }
 
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner GiteaScanner) WithFilter(filter common.RepoFilter) GiteaScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
+// WithArgs returns a copy of the scanner configured with the source
+// arguments in args, in the "key=value" form:
+//
+//   - topic=TOPIC only lists repositories tagged with TOPIC. It can be
+//     repeated, and a repository matches if it has any of the topics.
+//   - name=PATTERN only lists repositories whose name matches the glob PATTERN.
+func (scanner GiteaScanner) WithArgs(args []string) (GiteaScanner, error) {
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return scanner, fmt.Errorf("GiteaScanner: invalid argument %q, expected key=value", arg)
+		}
+
+		switch key {
+		case "topic":
+			scanner.topics = append(slices.Clip(scanner.topics), value)
+		case "name":
+			if _, err := path.Match(value, ""); err != nil {
+				return scanner, fmt.Errorf("GiteaScanner: invalid name pattern %q: %w", value, err)
+			}
+
+			scanner.namePattern = value
+		default:
+			return scanner, fmt.Errorf("GiteaScanner: unknown argument %q", key)
+		}
+	}
+
+	return scanner, nil
+}
+
 type giteaRepo struct {
-	Name          string `json:"name"`
-	FullName      string `json:"full_name"` //nolint:tagliatelle // Gitea API uses snake_case
-	Private       bool   `json:"private"`
-	Archived      bool   `json:"archived"`
-	DefaultBranch string `json:"default_branch"` //nolint:tagliatelle // Gitea API uses snake_case
-	HTMLURL       string `json:"html_url"`       //nolint:tagliatelle // Gitea API uses snake_case
-	CloneURL      string `json:"clone_url"`      //nolint:tagliatelle // Gitea API uses snake_case
-	Empty         bool   `json:"empty"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"` //nolint:tagliatelle // Gitea API uses snake_case
+	Private       bool      `json:"private"`
+	Archived      bool      `json:"archived"`
+	DefaultBranch string    `json:"default_branch"` //nolint:tagliatelle // Gitea API uses snake_case
+	HTMLURL       string    `json:"html_url"`       //nolint:tagliatelle // Gitea API uses snake_case
+	CloneURL      string    `json:"clone_url"`      //nolint:tagliatelle // Gitea API uses snake_case
+	Empty         bool      `json:"empty"`
+	Topics        []string  `json:"topics"`
+	UpdatedAt     time.Time `json:"updated_at"` //nolint:tagliatelle // Gitea API uses snake_case
 }
 
 type giteaSearchResult struct {
@@ -48,9 +99,9 @@ func (scanner GiteaScanner) List(
 
 	const limit = 50
 
-	fetchPage := giteaOrgReposPage
+	fetchPage := scanner.orgReposPage
 	if owner == "" {
-		fetchPage = giteaInstanceReposPage
+		fetchPage = scanner.instanceReposPage
 	}
 
 	for page := 1; ; page++ {
@@ -60,6 +111,12 @@ func (scanner GiteaScanner) List(
 		}
 
 		for _, repo := range repos {
+			if !scanner.matches(repo) || !scanner.filter.Match(giteaRepoInfo(repo)) {
+				log.Debugf("GiteaScanner: skipping %s, not matching the source filters", repo.FullName)
+
+				continue
+			}
+
 			if err := addGiteaRepo(nil, repo, publisher, repositories); err != nil {
 				return err
 			}
@@ -131,7 +188,42 @@ func addGiteaRepo(
 	return nil
 }
 
-func giteaOrgReposPage(base *url.URL, owner string, limit, page int) ([]giteaRepo, error) {
+// matches reports whether repo satisfies the topic and name filters set
+// through WithArgs.
+func (scanner GiteaScanner) matches(repo giteaRepo) bool {
+	if len(scanner.topics) > 0 && !slices.ContainsFunc(repo.Topics, func(topic string) bool {
+		return slices.ContainsFunc(scanner.topics, func(want string) bool {
+			return strings.EqualFold(topic, want)
+		})
+	}) {
+		return false
+	}
+
+	if scanner.namePattern != "" {
+		if ok, _ := path.Match(scanner.namePattern, repo.Name); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func giteaRepoInfo(repo giteaRepo) common.RepoInfo {
+	visibility := "public"
+	if repo.Private {
+		visibility = "private"
+	}
+
+	return common.RepoInfo{
+		Name:       repo.FullName,
+		Topics:     repo.Topics,
+		Visibility: visibility,
+		Archived:   repo.Archived,
+		PushedAt:   repo.UpdatedAt,
+	}
+}
+
+func (scanner GiteaScanner) orgReposPage(base *url.URL, owner string, limit, page int) ([]giteaRepo, error) {
 	apiURL := fmt.Sprintf("%s://%s/api/v1/orgs/%s/repos?limit=%d&page=%d",
 		base.Scheme, base.Host, url.PathEscape(owner), limit, page)
 
@@ -147,10 +239,17 @@ func giteaOrgReposPage(base *url.URL, owner string, limit, page int) ([]giteaRep
 	return giteaFetchRepoList(userURL)
 }
 
-func giteaInstanceReposPage(base *url.URL, _ string, limit, page int) ([]giteaRepo, error) {
+// instanceReposPage lists the public repositories of the whole instance.
+// With exactly one topic filter set, the search is narrowed server side to
+// the repositories tagged with it.
+func (scanner GiteaScanner) instanceReposPage(base *url.URL, _ string, limit, page int) ([]giteaRepo, error) {
 	apiURL := fmt.Sprintf("%s://%s/api/v1/repos/search?limit=%d&page=%d",
 		base.Scheme, base.Host, limit, page)
 
+	if len(scanner.topics) == 1 {
+		apiURL += "&topic=true&q=" + url.QueryEscape(scanner.topics[0])
+	}
+
 	req, err := giteaNewRequest(apiURL)
 	if err != nil {
 		return nil, err
@@ -232,9 +331,19 @@ func giteaNewRequest(apiURL string) (*http.Request, error) {
 		return nil, fmt.Errorf("GiteaScanner: new request: %w", err)
 	}
 
-	if token := os.Getenv("GITEA_TOKEN"); token != "" {
+	if token := giteaToken(req.URL.Hostname()); token != "" {
 		req.Header.Set("Authorization", "token "+token)
 	}
 
 	return req, nil
 }
+
+// giteaToken returns the API token for host, preferring the host specific one
+// in CREDENTIALS over the global GITEA_TOKEN.
+func giteaToken(host string) string {
+	if token := internal.Credential(host); token != "" {
+		return token
+	}
+
+	return os.Getenv("GITEA_TOKEN")
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/italia/publiccode-crawler/v4/common"
 	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -326,3 +327,101 @@ func TestGiteaScanner_ScanGroupOfRepos_pagination(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, repositories, 51)
 }
+
+func TestGiteaScanner_ScanRepo_hostToken(t *testing.T) {
+	repo := giteaRepoJSON("myrepo", "myorg/myrepo", "main", false, false, false)
+
+	ts := newGiteaTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token s3cr3t", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(repo)
+	})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/myorg/myrepo")
+	require.NoError(t, err)
+
+	viper.Set("CREDENTIALS", map[string]string{repoURL.Hostname(): "s3cr3t"})
+	t.Cleanup(func() { viper.Set("CREDENTIALS", nil) })
+
+	repositories := make(chan common.Repository, 1)
+
+	sc := scanner.NewGiteaScanner()
+	err = sc.Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	assert.Len(t, repositories, 1)
+}
+
+func TestGiteaScanner_ScanGroupOfRepos_filters(t *testing.T) {
+	tagged := giteaRepoJSON("app-tagged", "org/app-tagged", "main", false, false, false)
+	tagged["topics"] = []string{"publiccode"}
+
+	otherName := giteaRepoJSON("lib-tagged", "org/lib-tagged", "main", false, false, false)
+	otherName["topics"] = []string{"PublicCode"}
+
+	repos := []map[string]any{
+		tagged,
+		otherName,
+		giteaRepoJSON("app-untagged", "org/app-untagged", "main", false, false, false),
+	}
+
+	ts := newGiteaTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(repos)
+	})
+	defer ts.Close()
+
+	groupURL, err := url.Parse(ts.URL + "/org")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	sc, err := scanner.NewGiteaScanner().WithArgs([]string{"topic=publiccode", "name=app-*"})
+	require.NoError(t, err)
+
+	err = sc.List(*groupURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	got := <-repositories
+	assert.Equal(t, "org/app-tagged", got.Name)
+}
+
+func TestGiteaScanner_ScanGroupOfRepos_instanceTopic(t *testing.T) {
+	tagged := giteaRepoJSON("repo1", "org/repo1", "main", false, false, false)
+	tagged["topics"] = []string{"publiccode"}
+
+	ts := newGiteaTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/repos/search", r.URL.Path)
+		assert.Equal(t, "publiccode", r.URL.Query().Get("q"))
+		assert.Equal(t, "true", r.URL.Query().Get("topic"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{tagged}})
+	})
+	defer ts.Close()
+
+	instanceURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	sc, err := scanner.NewGiteaScanner().WithArgs([]string{"topic=publiccode"})
+	require.NoError(t, err)
+
+	err = sc.List(*instanceURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	assert.Len(t, repositories, 1)
+}
+
+func TestGiteaScanner_WithArgs_invalid(t *testing.T) {
+	for _, args := range [][]string{{"topic"}, {"unknown=x"}, {"name=["}} {
+		_, err := scanner.NewGiteaScanner().WithArgs(args)
+
+		assert.Error(t, err, args)
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 type GitHubScanner struct {
 	client *github.Client
+	filter common.RepoFilter
 }
 
 // NewGitHubScanner returns a new GitHubScanner using the
@@ -39,6 +40,14 @@ func NewGitHubScanner() GitHubScanner {
 	return GitHubScanner{client: client}
 }
 
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner GitHubScanner) WithFilter(filter common.RepoFilter) GitHubScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
 // List scans a GitHub organization represented by url, associated to
 // publisher and sends any repository containing a publiccode.yml to the repositories
 // channel as a [common.Repository].
@@ -103,6 +112,12 @@ func (scanner GitHubScanner) List(
 				continue
 			}
 
+			if !scanner.filter.Match(gitHubRepoInfo(r)) {
+				log.Debugf("GitHubScanner: skipping %s, not matching the source filters", r.GetFullName())
+
+				continue
+			}
+
 			if err = scanner.Scan(*repoURL, publisher, repositories); err != nil {
 				if errors.Is(err, ErrPubliccodeNotFound) {
 					log.Warnf("can't scan repository %s: %s", repoURL.String(), err.Error())
@@ -215,6 +230,24 @@ Retry:
 	return nil
 }
 
+func gitHubRepoInfo(repo *github.Repository) common.RepoInfo {
+	visibility := repo.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if repo.GetPrivate() {
+			visibility = "private"
+		}
+	}
+
+	return common.RepoInfo{
+		Name:       repo.GetFullName(),
+		Topics:     repo.Topics,
+		Visibility: visibility,
+		Archived:   repo.GetArchived(),
+		PushedAt:   repo.GetPushedAt().Time,
+	}
+}
+
 func secondaryRateLimit(err *github.AbuseRateLimitError) {
 	var duration time.Duration
 	if err.RetryAfter != nil {
@@ -12,12 +12,22 @@ import (
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
-type GitLabScanner struct{}
+type GitLabScanner struct {
+	filter common.RepoFilter
+}
 
 func NewGitLabScanner() GitLabScanner {
 	return GitLabScanner{}
 }
 
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner GitLabScanner) WithFilter(filter common.RepoFilter) GitLabScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
 // List scans a GitLab group represented by url.
 func (scanner GitLabScanner) List(
 	url url.URL, publisher common.Publisher, repositories chan common.Repository,
@@ -40,7 +50,7 @@ func (scanner GitLabScanner) List(
 			return fmt.Errorf("can't get GitLab group '%s': %w", groupName, err)
 		}
 
-		if err = addGroupProjects(*group, publisher, repositories, git); err != nil {
+		if err = addGroupProjects(*group, publisher, repositories, git, scanner.filter); err != nil {
 			return err
 		}
 	default:
@@ -58,7 +68,7 @@ func (scanner GitLabScanner) List(
 			}
 
 			for _, g := range groups {
-				if err = addGroupProjects(*g, publisher, repositories, git); err != nil {
+				if err = addGroupProjects(*g, publisher, repositories, git, scanner.filter); err != nil {
 					return err
 				}
 			}
@@ -120,10 +130,11 @@ func generateGitlabRawURL(baseURL, defaultBranch string) (string, error) {
 	return parsedURL.String(), err
 }
 
-// addGroupProjects sends all the projects in a GitLab group, including all subgroups, to
-// the repositories channel.
+// addGroupProjects sends all the projects in a GitLab group matching filter, including
+// all subgroups, to the repositories channel.
 func addGroupProjects(
 	group gitlab.Group, publisher common.Publisher, repositories chan common.Repository, client *gitlab.Client,
+	filter common.RepoFilter,
 ) error {
 	opts := &gitlab.ListGroupProjectsOptions{
 		ListOptions: gitlab.ListOptions{Page: 1},
@@ -136,6 +147,12 @@ func addGroupProjects(
 		}
 
 		for _, prj := range projects {
+			if !filter.Match(gitLabRepoInfo(*prj)) {
+				log.Debugf("GitLabScanner: skipping %s, not matching the source filters", prj.PathWithNamespace)
+
+				continue
+			}
+
 			err = addProject(nil, *prj, publisher, repositories)
 			if err != nil {
 				return err
@@ -160,7 +177,7 @@ func addGroupProjects(
 		}
 
 		for _, g := range groups {
-			err = addGroupProjects(*g, publisher, repositories, client)
+			err = addGroupProjects(*g, publisher, repositories, client, filter)
 			if err != nil {
 				return err
 			}
@@ -176,6 +193,21 @@ func addGroupProjects(
 	return nil
 }
 
+func gitLabRepoInfo(project gitlab.Project) common.RepoInfo {
+	info := common.RepoInfo{
+		Name:       project.PathWithNamespace,
+		Topics:     project.Topics,
+		Visibility: string(project.Visibility),
+		Archived:   project.Archived,
+	}
+
+	if project.LastActivityAt != nil {
+		info.PushedAt = *project.LastActivityAt
+	}
+
+	return info
+}
+
 // addGroupProjects sends the GitLab project the repositories channel.
 func addProject(
 	originalURL *url.URL, project gitlab.Project, publisher common.Publisher, repositories chan common.Repository,
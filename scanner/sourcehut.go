@@ -0,0 +1,248 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/internal"
+	log "github.com/sirupsen/logrus"
+)
+
+const sourceHutRepositoryFields = `name visibility updated HEAD { name } owner { canonicalName }`
+
+const sourceHutListQuery = `query repositories($username: String!, $cursor: Cursor) {
+  user(username: $username) {
+    repositories(cursor: $cursor) {
+      results { ` + sourceHutRepositoryFields + ` }
+      cursor
+    }
+  }
+}`
+
+const sourceHutRepoQuery = `query repository($username: String!, $name: String!) {
+  user(username: $username) {
+    repository(name: $name) { ` + sourceHutRepositoryFields + ` }
+  }
+}`
+
+// SourceHutScanner scans git.sr.ht, or a self hosted git.sr.ht instance,
+// through its GraphQL API. Requests are authenticated with the token
+// configured for the host in CREDENTIALS, falling back to the SRHT_TOKEN
+// environment variable.
+type SourceHutScanner struct {
+	filter common.RepoFilter
+}
+
+func NewSourceHutScanner() SourceHutScanner {
+	return SourceHutScanner{}
+}
+
+// WithFilter returns a copy of the scanner listing only the repositories
+// matching filter.
+func (scanner SourceHutScanner) WithFilter(filter common.RepoFilter) SourceHutScanner {
+	scanner.filter = filter
+
+	return scanner
+}
+
+type sourceHutRepo struct {
+	Name       string    `json:"name"`
+	Visibility string    `json:"visibility"`
+	Updated    time.Time `json:"updated"`
+	HEAD       *struct {
+		Name string `json:"name"`
+	} `json:"HEAD"` //nolint:tagliatelle // sr.ht GraphQL schema
+	Owner struct {
+		CanonicalName string `json:"canonicalName"`
+	} `json:"owner"`
+}
+
+type sourceHutUser struct {
+	Repositories struct {
+		Results []sourceHutRepo `json:"results"`
+		Cursor  *string         `json:"cursor"`
+	} `json:"repositories"`
+	Repository *sourceHutRepo `json:"repository"`
+}
+
+type sourceHutResponse struct {
+	Data struct {
+		User *sourceHutUser `json:"user"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// List scans all the repositories of the SourceHut user (~user) represented
+// by groupURL.
+func (scanner SourceHutScanner) List(
+	groupURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	log.Debugf("SourceHutScanner.List(%s)", groupURL.String())
+
+	splitted := strings.Split(strings.Trim(groupURL.Path, "/"), "/")
+	if len(splitted) != 1 || !strings.HasPrefix(splitted[0], "~") {
+		return fmt.Errorf("SourceHutScanner: doesn't look like a SourceHut user %s", groupURL.String())
+	}
+
+	variables := map[string]any{"username": strings.TrimPrefix(splitted[0], "~")}
+
+	for {
+		user, err := sourceHutQuery(groupURL, sourceHutListQuery, variables)
+		if err != nil {
+			return fmt.Errorf("SourceHutScanner: %w", err)
+		}
+
+		for _, repo := range user.Repositories.Results {
+			info := common.RepoInfo{
+				Name:       strings.TrimPrefix(repo.Owner.CanonicalName, "~") + "/" + repo.Name,
+				Visibility: strings.ToLower(repo.Visibility),
+				PushedAt:   repo.Updated,
+			}
+
+			if !scanner.filter.Match(info) {
+				log.Debugf("SourceHutScanner: skipping %s, not matching the source filters", info.Name)
+
+				continue
+			}
+
+			if err := addSourceHutRepo(groupURL, nil, repo, publisher, repositories); err != nil {
+				return err
+			}
+		}
+
+		if user.Repositories.Cursor == nil {
+			break
+		}
+
+		variables["cursor"] = *user.Repositories.Cursor
+	}
+
+	return nil
+}
+
+// Scan scans a single SourceHut repository represented by repoURL.
+func (scanner SourceHutScanner) Scan(
+	repoURL url.URL, publisher common.Publisher, repositories chan common.Repository,
+) error {
+	log.Debugf("SourceHutScanner.Scan(%s)", repoURL.String())
+
+	splitted := strings.Split(strings.TrimSuffix(strings.Trim(repoURL.Path, "/"), ".git"), "/")
+	if len(splitted) != 2 || !strings.HasPrefix(splitted[0], "~") {
+		return fmt.Errorf("SourceHutScanner: doesn't look like a SourceHut repo %s", repoURL.String())
+	}
+
+	user, err := sourceHutQuery(repoURL, sourceHutRepoQuery, map[string]any{
+		"username": strings.TrimPrefix(splitted[0], "~"),
+		"name":     splitted[1],
+	})
+	if err != nil {
+		return fmt.Errorf("SourceHutScanner: %w", err)
+	}
+
+	if user.Repository == nil {
+		return fmt.Errorf("SourceHutScanner: repo %s not found", repoURL.String())
+	}
+
+	return addSourceHutRepo(repoURL, &repoURL, *user.Repository, publisher, repositories)
+}
+
+func addSourceHutRepo(
+	base url.URL, originalURL *url.URL, repo sourceHutRepo,
+	publisher common.Publisher, repositories chan common.Repository,
+) error {
+	if repo.Visibility != "PUBLIC" || repo.HEAD == nil {
+		return nil
+	}
+
+	branch := strings.TrimPrefix(repo.HEAD.Name, "refs/heads/")
+
+	canonicalURL, err := url.Parse(
+		fmt.Sprintf("%s://%s/%s/%s", base.Scheme, base.Host, repo.Owner.CanonicalName, repo.Name),
+	)
+	if err != nil {
+		return fmt.Errorf("SourceHutScanner: failed to get canonical repo URL for %s: %w", repo.Name, err)
+	}
+
+	if originalURL == nil {
+		originalURL = canonicalURL
+	}
+
+	repositories <- common.Repository{
+		Name:         strings.TrimPrefix(repo.Owner.CanonicalName, "~") + "/" + repo.Name,
+		FileRawURL:   canonicalURL.String() + "/blob/" + branch + "/publiccode.yml",
+		URL:          *originalURL,
+		CanonicalURL: *canonicalURL,
+		GitBranch:    branch,
+		Publisher:    publisher,
+	}
+
+	return nil
+}
+
+// sourceHutQuery runs a GraphQL query against the git.sr.ht API of the
+// instance hosting u and returns the queried user.
+func sourceHutQuery(u url.URL, query string, variables map[string]any) (*sourceHutUser, error) {
+	apiURL := fmt.Sprintf("%s://%s/query", u.Scheme, u.Host)
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return nil, fmt.Errorf("encode query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token := sourceHutToken(u.Hostname()); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("POST %s: status %d", apiURL, resp.StatusCode)
+	}
+
+	var result sourceHutResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("POST %s: %s", apiURL, result.Errors[0].Message)
+	}
+
+	if result.Data.User == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return result.Data.User, nil
+}
+
+// sourceHutToken returns the API token for host, preferring the host specific
+// one in CREDENTIALS over the global SRHT_TOKEN.
+func sourceHutToken(host string) string {
+	if token := internal.Credential(host); token != "" {
+		return token
+	}
+
+	return os.Getenv("SRHT_TOKEN")
+}
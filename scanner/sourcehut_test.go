@@ -0,0 +1,162 @@
+package scanner_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/common"
+	"github.com/italia/publiccode-crawler/v4/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sourceHutRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func sourceHutRepoJSON(owner, name, visibility, head string) map[string]any {
+	repo := map[string]any{
+		"name":       name,
+		"visibility": visibility,
+		"owner":      map[string]any{"canonicalName": "~" + owner},
+		"HEAD":       nil,
+	}
+
+	if head != "" {
+		repo["HEAD"] = map[string]any{"name": "refs/heads/" + head}
+	}
+
+	return repo
+}
+
+// newSourceHutTestServer returns a test server answering GraphQL queries with
+// the user returned by handler.
+func newSourceHutTestServer(
+	t *testing.T, handler func(req sourceHutRequest) map[string]any,
+) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/query", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+
+		var req sourceHutRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{"user": handler(req)},
+		})
+	}))
+}
+
+func TestSourceHutScanner_Scan_success(t *testing.T) {
+	ts := newSourceHutTestServer(t, func(req sourceHutRequest) map[string]any {
+		assert.Equal(t, "alice", req.Variables["username"])
+		assert.Equal(t, "myrepo", req.Variables["name"])
+
+		return map[string]any{"repository": sourceHutRepoJSON("alice", "myrepo", "PUBLIC", "trunk")}
+	})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/~alice/myrepo")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewSourceHutScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 1)
+
+	got := <-repositories
+	assert.Equal(t, "alice/myrepo", got.Name)
+	assert.Equal(t, "trunk", got.GitBranch)
+	assert.Equal(t, ts.URL+"/~alice/myrepo/blob/trunk/publiccode.yml", got.FileRawURL)
+	assert.Equal(t, ts.URL+"/~alice/myrepo", got.CanonicalURL.String())
+}
+
+func TestSourceHutScanner_Scan_notFound(t *testing.T) {
+	ts := newSourceHutTestServer(t, func(_ sourceHutRequest) map[string]any {
+		return map[string]any{"repository": nil}
+	})
+	defer ts.Close()
+
+	repoURL, err := url.Parse(ts.URL + "/~alice/missing")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 1)
+
+	err = scanner.NewSourceHutScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.Error(t, err)
+	assert.Empty(t, repositories)
+}
+
+func TestSourceHutScanner_Scan_invalidURL(t *testing.T) {
+	repoURL, _ := url.Parse("https://git.sr.ht/alice/myrepo")
+
+	repositories := make(chan common.Repository, 1)
+
+	err := scanner.NewSourceHutScanner().Scan(*repoURL, giteaPublisher(), repositories)
+
+	require.Error(t, err)
+}
+
+func TestSourceHutScanner_List_pagination(t *testing.T) {
+	ts := newSourceHutTestServer(t, func(req sourceHutRequest) map[string]any {
+		assert.Equal(t, "alice", req.Variables["username"])
+
+		if req.Variables["cursor"] == "next" {
+			return map[string]any{"repositories": map[string]any{
+				"results": []map[string]any{sourceHutRepoJSON("alice", "last", "PUBLIC", "main")},
+				"cursor":  nil,
+			}}
+		}
+
+		return map[string]any{"repositories": map[string]any{
+			"results": []map[string]any{
+				sourceHutRepoJSON("alice", "first", "PUBLIC", "master"),
+				sourceHutRepoJSON("alice", "secret", "PRIVATE", "master"),
+				sourceHutRepoJSON("alice", "hidden", "UNLISTED", "master"),
+				sourceHutRepoJSON("alice", "empty", "PUBLIC", ""),
+			},
+			"cursor": "next",
+		}}
+	})
+	defer ts.Close()
+
+	groupURL, err := url.Parse(ts.URL + "/~alice")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	err = scanner.NewSourceHutScanner().List(*groupURL, giteaPublisher(), repositories)
+
+	require.NoError(t, err)
+	require.Len(t, repositories, 2)
+
+	assert.Equal(t, "alice/first", (<-repositories).Name)
+	assert.Equal(t, "alice/last", (<-repositories).Name)
+}
+
+func TestSourceHutScanner_List_graphQLError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"errors": []map[string]any{{"message": "Authentication required"}},
+		})
+	}))
+	defer ts.Close()
+
+	groupURL, err := url.Parse(ts.URL + "/~alice")
+	require.NoError(t, err)
+
+	repositories := make(chan common.Repository, 10)
+
+	err = scanner.NewSourceHutScanner().List(*groupURL, giteaPublisher(), repositories)
+
+	require.ErrorContains(t, err, "Authentication required")
+}
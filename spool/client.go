@@ -0,0 +1,152 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// Client is an apiclient.Client saving the software and log writes that
+// fail because the API is unavailable to a Spool. The writes still return
+// their errors.
+type Client struct {
+	apiclient.Client
+
+	spool *Spool
+}
+
+// Wrap returns client spooling its failed writes to spool.
+func Wrap(client apiclient.Client, spool *Spool) *Client {
+	return &Client{Client: client, spool: spool}
+}
+
+func (c *Client) PostSoftware(
+	ctx context.Context, url string, aliases []string, publiccodeYml string, active bool,
+) (*apiclient.Software, error) {
+	software, err := c.Client.PostSoftware(ctx, url, aliases, publiccodeYml, active)
+
+	return software, c.spoolSoftware(err, OpPostSoftware, "", "", url, aliases, publiccodeYml, active)
+}
+
+func (c *Client) PatchSoftware(
+	ctx context.Context, softwareID string, url string, aliases []string, publiccodeYml string,
+) error {
+	err := c.Client.PatchSoftware(ctx, softwareID, url, aliases, publiccodeYml)
+
+	return c.spoolSoftware(err, OpPatchSoftware, "", softwareID, url, aliases, publiccodeYml, false)
+}
+
+func (c *Client) PostSoftwareLog(ctx context.Context, softwareID string, message string) error {
+	err := c.Client.PostSoftwareLog(ctx, softwareID, message)
+
+	return c.spoolLog(err, OpPostSoftwareLog, "", softwareID, message)
+}
+
+func (c *Client) PostLog(ctx context.Context, message string) error {
+	return c.spoolLog(c.Client.PostLog(ctx, message), OpPostLog, "", "", message)
+}
+
+func (c *Client) PostCatalogSoftware(
+	ctx context.Context, catalogID string, softwareURL string, aliases []string, publiccodeYml string, active bool,
+) (*apiclient.Software, error) {
+	software, err := c.Client.PostCatalogSoftware(ctx, catalogID, softwareURL, aliases, publiccodeYml, active)
+
+	return software, c.spoolSoftware(err, OpPostSoftware, catalogID, "", softwareURL, aliases, publiccodeYml, active)
+}
+
+func (c *Client) PatchCatalogSoftware(
+	ctx context.Context,
+	catalogID string, softwareID string, softwareURL string, aliases []string, publiccodeYml string,
+) error {
+	err := c.Client.PatchCatalogSoftware(ctx, catalogID, softwareID, softwareURL, aliases, publiccodeYml)
+
+	return c.spoolSoftware(err, OpPatchSoftware, catalogID, softwareID, softwareURL, aliases, publiccodeYml, false)
+}
+
+func (c *Client) PostCatalogSoftwareLog(
+	ctx context.Context, catalogID string, softwareID string, message string,
+) error {
+	err := c.Client.PostCatalogSoftwareLog(ctx, catalogID, softwareID, message)
+
+	return c.spoolLog(err, OpPostSoftwareLog, catalogID, softwareID, message)
+}
+
+func (c *Client) PostCatalogLog(ctx context.Context, catalogID string, message string) error {
+	return c.spoolLog(c.Client.PostCatalogLog(ctx, catalogID, message), OpPostLog, catalogID, "", message)
+}
+
+func (c *Client) spoolSoftware(
+	err error, op Op, catalogID, softwareID, url string, aliases []string, publiccodeYml string, active bool,
+) error {
+	if !apiclient.IsUnavailable(err) {
+		return err
+	}
+
+	payload := SoftwarePayload{URL: url, Aliases: aliases, PubliccodeYml: publiccodeYml, Active: active}
+
+	return c.append(err, op, catalogID, softwareID, payload)
+}
+
+func (c *Client) spoolLog(err error, op Op, catalogID, softwareID, message string) error {
+	if !apiclient.IsUnavailable(err) {
+		return err
+	}
+
+	return c.append(err, op, catalogID, softwareID, LogPayload{Message: message})
+}
+
+// append spools the write that failed with err, and returns err noting
+// whether it was spooled.
+func (c *Client) append(err error, op Op, catalogID, softwareID string, payload any) error {
+	data, jsonErr := json.Marshal(payload)
+	if jsonErr != nil {
+		return fmt.Errorf("%w (can't spool: %w)", err, jsonErr)
+	}
+
+	method, endpoint := Endpoint(op, catalogID, softwareID)
+
+	spoolErr := c.spool.Append(Entry{
+		Op:         op,
+		Method:     method,
+		Endpoint:   endpoint,
+		CatalogID:  catalogID,
+		SoftwareID: softwareID,
+		Payload:    data,
+		SpooledAt:  time.Now().UTC(),
+	})
+	if spoolErr != nil {
+		log.Error(spoolErr)
+
+		return fmt.Errorf("%w (can't spool: %w)", err, spoolErr)
+	}
+
+	return fmt.Errorf("%w (spooled for later)", err)
+}
+
+// Endpoint returns the HTTP method and the path, relative to the API base
+// URL, of op.
+func Endpoint(op Op, catalogID, softwareID string) (string, string) {
+	base := "/"
+	if catalogID != "" {
+		base = path.Join("/catalogs", catalogID)
+	}
+
+	switch op {
+	case OpPostSoftware:
+		return http.MethodPost, path.Join(base, "software")
+	case OpPatchSoftware:
+		return http.MethodPatch, path.Join(base, "software", softwareID)
+	case OpPostSoftwareLog:
+		return http.MethodPost, path.Join(base, "software", softwareID, "logs")
+	case OpPostLog:
+		return http.MethodPost, path.Join(base, "logs")
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,196 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// Result counts what Replay did with the spooled writes.
+type Result struct {
+	// Applied writes were made to the API.
+	Applied int
+	// Superseded writes were dropped for a later write of the same software.
+	Superseded int
+	// Refused writes were dropped because the API refused them.
+	Refused int
+	// Remaining writes are still in the spool, as the API was unavailable.
+	Remaining int
+}
+
+// Compact returns entries without the writes superseded by a later one of
+// the same software: PATCHes by a later PATCH of the same software ID, POSTs
+// by a later POST of the same URL, in the same catalog. Logs are all kept.
+// The order is preserved.
+func Compact(entries []Entry) []Entry {
+	seen := map[string]bool{}
+	keep := make([]bool, len(entries))
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		key := supersedeKey(entries[i])
+		if key == "" || !seen[key] {
+			keep[i] = true
+			seen[key] = key != ""
+		}
+	}
+
+	compacted := make([]Entry, 0, len(entries))
+
+	for i, entry := range entries {
+		if keep[i] {
+			compacted = append(compacted, entry)
+		}
+	}
+
+	return compacted
+}
+
+// supersedeKey returns the key identifying the software entry writes, or
+// "" for the writes that are never superseded.
+func supersedeKey(entry Entry) string {
+	switch entry.Op {
+	case OpPatchSoftware:
+		return fmt.Sprintf("%s %s %s", entry.Op, entry.CatalogID, entry.SoftwareID)
+	case OpPostSoftware:
+		var payload SoftwarePayload
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s %s %s", entry.Op, entry.CatalogID, payload.URL)
+	default:
+		return ""
+	}
+}
+
+// Replay applies the spooled writes, compacted, in order with client and
+// removes them from the spool. It stops at the first write failing because
+// the API is still unavailable, leaving it and the ones after it in the
+// spool. Writes the API refuses are logged and dropped.
+//
+// A spooled POST of software the API already has, eg. because the API
+// created it but failed to reply, is applied as a PATCH.
+func Replay(ctx context.Context, client apiclient.Client, spool *Spool) (Result, error) {
+	// Failures must not go back to the spool being replayed.
+	if spooling, ok := client.(*Client); ok {
+		client = spooling.Client
+	}
+
+	var result Result
+
+	err := spool.Take(func(entries []Entry) []Entry {
+		compacted := Compact(entries)
+		result.Superseded = len(entries) - len(compacted)
+
+		for i, entry := range compacted {
+			err := apply(ctx, client, entry)
+
+			switch {
+			case err == nil:
+				result.Applied++
+			case ctx.Err() != nil || apiclient.IsUnavailable(err):
+				log.Warnf("can't replay %s %s, keeping it and the following writes: %s", entry.Method, entry.Endpoint, err)
+
+				result.Remaining = len(compacted) - i
+
+				return compacted[i:]
+			default:
+				log.Errorf("dropping spooled %s %s: %s", entry.Method, entry.Endpoint, err)
+
+				result.Refused++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func apply(ctx context.Context, client apiclient.Client, entry Entry) error {
+	switch entry.Op {
+	case OpPostSoftware, OpPatchSoftware:
+		var payload SoftwarePayload
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+
+		if entry.Op == OpPostSoftware {
+			return postSoftware(ctx, client, entry.CatalogID, payload)
+		}
+
+		return patchSoftware(ctx, client, entry.CatalogID, entry.SoftwareID, payload)
+	case OpPostSoftwareLog, OpPostLog:
+		var payload LogPayload
+		if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+
+		switch {
+		case entry.Op == OpPostSoftwareLog && entry.CatalogID != "":
+			return client.PostCatalogSoftwareLog(ctx, entry.CatalogID, entry.SoftwareID, payload.Message)
+		case entry.Op == OpPostSoftwareLog:
+			return client.PostSoftwareLog(ctx, entry.SoftwareID, payload.Message)
+		case entry.CatalogID != "":
+			return client.PostCatalogLog(ctx, entry.CatalogID, payload.Message)
+		default:
+			return client.PostLog(ctx, payload.Message)
+		}
+	default:
+		return errors.New("unknown operation " + string(entry.Op))
+	}
+}
+
+func postSoftware(ctx context.Context, client apiclient.Client, catalogID string, payload SoftwarePayload) error {
+	var (
+		existing *apiclient.Software
+		err      error
+	)
+
+	if catalogID != "" {
+		existing, err = client.GetCatalogSoftwareByURL(ctx, catalogID, payload.URL)
+	} else {
+		existing, err = client.GetSoftwareByURL(ctx, payload.URL)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return patchSoftware(ctx, client, catalogID, existing.ID, payload)
+	}
+
+	if catalogID != "" {
+		_, err = client.PostCatalogSoftware(
+			ctx, catalogID, payload.URL, payload.Aliases, payload.PubliccodeYml, payload.Active,
+		)
+	} else {
+		_, err = client.PostSoftware(ctx, payload.URL, payload.Aliases, payload.PubliccodeYml, payload.Active)
+	}
+
+	return err
+}
+
+func patchSoftware(
+	ctx context.Context, client apiclient.Client, catalogID, softwareID string, payload SoftwarePayload,
+) error {
+	if catalogID != "" {
+		return client.PatchCatalogSoftware(
+			ctx, catalogID, softwareID, payload.URL, payload.Aliases, payload.PubliccodeYml,
+		)
+	}
+
+	return client.PatchSoftware(ctx, softwareID, payload.URL, payload.Aliases, payload.PubliccodeYml)
+}
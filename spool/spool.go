@@ -0,0 +1,216 @@
+// Package spool keeps the writes to the API that failed because the API was
+// unavailable, so that they can be applied later instead of losing the
+// results of a crawl.
+//
+// The spool is an NDJSON file, one write per line in the order they were
+// made, with the operation, the endpoint it targets and its payload.
+package spool
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the crawler's spool in DATADIR.
+const FileName = "spool.ndjson"
+
+// Op is a write operation on the API.
+type Op string
+
+// Operations, named after the apiclient.Client methods. The Catalog
+// variants are the same operations with CatalogID set.
+const (
+	OpPostSoftware    Op = "PostSoftware"
+	OpPatchSoftware   Op = "PatchSoftware"
+	OpPostSoftwareLog Op = "PostSoftwareLog"
+	OpPostLog         Op = "PostLog"
+)
+
+// Entry is a spooled write.
+type Entry struct {
+	Op     Op     `json:"op"`
+	Method string `json:"method"`
+	// Endpoint is the path of the write, relative to the API base URL.
+	Endpoint   string `json:"endpoint"`
+	CatalogID  string `json:"catalogId,omitempty"`
+	SoftwareID string `json:"softwareId,omitempty"`
+	// Payload is the body of the write: a SoftwarePayload for software
+	// writes, a LogPayload for logs.
+	Payload   json.RawMessage `json:"payload"`
+	SpooledAt time.Time       `json:"spooledAt"`
+}
+
+// SoftwarePayload is the payload of software writes. Active isn't sent by
+// PATCHes.
+type SoftwarePayload struct {
+	URL           string   `json:"url"`
+	Aliases       []string `json:"aliases"`
+	PubliccodeYml string   `json:"publiccodeYml"`
+	Active        bool     `json:"active"`
+}
+
+// LogPayload is the payload of log writes.
+type LogPayload struct {
+	Message string `json:"message"`
+}
+
+// Spool is a spool file. It's safe for concurrent use.
+type Spool struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New returns the spool at path. The file is created at the first Append.
+func New(path string) *Spool {
+	return &Spool{path: path}
+}
+
+// Path returns the path of the spool file.
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Append adds entry at the end of the spool, syncing it to disk.
+func (s *Spool) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("can't spool %s: %w", entry.Op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("can't open spool: %w", err)
+	}
+
+	if _, err = file.Write(append(line, '\n')); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("can't write to spool %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// Entries returns the entries in the spool, in the order they were added.
+// A missing spool has no entries.
+func (s *Spool) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+// Take calls fn with the entries in the spool and replaces them with the
+// ones fn returns. Appends wait for Take to finish.
+func (s *Spool) Take(fn func([]Entry) []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	return s.write(fn(entries))
+}
+
+func (s *Spool) read() ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't open spool: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid spool entry: %w", s.path, line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read spool %s: %w", s.path, err)
+	}
+
+	return entries, nil
+}
+
+// write replaces the spool with entries, removing it if there are none.
+// The new spool is written beside the old one and renamed over it, so a
+// crash leaves one or the other.
+func (s *Spool) write(entries []Entry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("can't remove spool: %w", err)
+		}
+
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("can't rewrite spool: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+
+	for _, entry := range entries {
+		if err = enc.Encode(entry); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("can't rewrite spool %s: %w", s.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package spool_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/apiclient"
+	"github.com/italia/publiccode-crawler/v4/spool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyClient is a MemoryClient whose writes fail with err, if set.
+type flakyClient struct {
+	*apiclient.MemoryClient
+
+	err error
+}
+
+func (c *flakyClient) PostSoftware(
+	ctx context.Context, url string, aliases []string, yml string, active bool,
+) (*apiclient.Software, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.MemoryClient.PostSoftware(ctx, url, aliases, yml, active)
+}
+
+func (c *flakyClient) PatchSoftware(
+	ctx context.Context, softwareID string, url string, aliases []string, yml string,
+) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.MemoryClient.PatchSoftware(ctx, softwareID, url, aliases, yml)
+}
+
+func (c *flakyClient) PostLog(ctx context.Context, message string) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.MemoryClient.PostLog(ctx, message)
+}
+
+func (c *flakyClient) PostCatalogLog(ctx context.Context, catalogID string, message string) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.MemoryClient.PostCatalogLog(ctx, catalogID, message)
+}
+
+var errDown = &url.Error{Op: "Post", URL: "https://api.example.org/software", Err: errors.New("connection refused")}
+
+func newSpool(t *testing.T) *spool.Spool {
+	t.Helper()
+
+	return spool.New(filepath.Join(t.TempDir(), spool.FileName))
+}
+
+func TestClientSpoolsUnavailableWrites(t *testing.T) {
+	sp := newSpool(t)
+	flaky := &flakyClient{MemoryClient: apiclient.NewMemoryClient(), err: errDown}
+	client := spool.Wrap(flaky, sp)
+
+	_, err := client.PostSoftware(t.Context(), "https://example.org/app", []string{"a"}, "yml", true)
+	require.ErrorIs(t, err, errDown)
+	assert.Contains(t, err.Error(), "spooled")
+
+	require.Error(t, client.PatchSoftware(t.Context(), "id", "https://example.org/b", nil, "yml"))
+	require.Error(t, client.PostCatalogLog(t.Context(), "cat", "message"))
+
+	// Refused writes would fail again, they aren't spooled.
+	flaky.err = &apiclient.HTTPError{StatusCode: http.StatusUnprocessableEntity, Status: "422 Unprocessable Entity"}
+	require.Error(t, client.PostLog(t.Context(), "refused"))
+
+	entries, err := sp.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, spool.OpPostSoftware, entries[0].Op)
+	assert.Equal(t, "POST", entries[0].Method)
+	assert.Equal(t, "/software", entries[0].Endpoint)
+	assert.JSONEq(
+		t, `{"url": "https://example.org/app", "aliases": ["a"], "publiccodeYml": "yml", "active": true}`,
+		string(entries[0].Payload),
+	)
+
+	assert.Equal(t, "PATCH", entries[1].Method)
+	assert.Equal(t, "/software/id", entries[1].Endpoint)
+	assert.Equal(t, "id", entries[1].SoftwareID)
+
+	assert.Equal(t, "/catalogs/cat/logs", entries[2].Endpoint)
+	assert.Equal(t, "cat", entries[2].CatalogID)
+	assert.JSONEq(t, `{"message": "message"}`, string(entries[2].Payload))
+}
+
+func TestCompact(t *testing.T) {
+	entries := []spool.Entry{
+		{Op: spool.OpPatchSoftware, SoftwareID: "1", Payload: []byte(`{"url": "a"}`)},
+		{Op: spool.OpPostSoftware, Payload: []byte(`{"url": "b"}`)},
+		{Op: spool.OpPostSoftwareLog, SoftwareID: "1", Payload: []byte(`{"message": "first"}`)},
+		{Op: spool.OpPatchSoftware, SoftwareID: "1", Payload: []byte(`{"url": "a2"}`)},
+		{Op: spool.OpPatchSoftware, SoftwareID: "1", CatalogID: "cat", Payload: []byte(`{"url": "a"}`)},
+		{Op: spool.OpPostSoftware, Payload: []byte(`{"url": "b", "publiccodeYml": "new"}`)},
+		{Op: spool.OpPostSoftwareLog, SoftwareID: "1", Payload: []byte(`{"message": "second"}`)},
+	}
+
+	assert.Equal(t, []spool.Entry{entries[2], entries[3], entries[4], entries[5], entries[6]}, spool.Compact(entries))
+}
+
+func TestReplay(t *testing.T) {
+	sp := newSpool(t)
+	flaky := &flakyClient{MemoryClient: apiclient.NewMemoryClient(), err: errDown}
+	client := spool.Wrap(flaky, sp)
+
+	// The API created this one but the reply was lost.
+	existing, err := flaky.MemoryClient.PostSoftware(t.Context(), "https://example.org/existing", nil, "old", true)
+	require.NoError(t, err)
+
+	_, _ = client.PostSoftware(t.Context(), "https://example.org/app", nil, "v1", false)
+	_, _ = client.PostSoftware(t.Context(), "https://example.org/app", nil, "v2", false)
+	_, _ = client.PostSoftware(t.Context(), "https://example.org/existing", nil, "new", true)
+	_ = client.PatchSoftware(t.Context(), "missing", "https://example.org/missing", nil, "")
+	_ = client.PostLog(t.Context(), "log")
+
+	// Still down: everything stays in the spool.
+	result, err := spool.Replay(t.Context(), client, sp)
+	require.NoError(t, err)
+	assert.Equal(t, spool.Result{Superseded: 1, Remaining: 4}, result)
+
+	entries, err := sp.Entries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	flaky.err = nil
+
+	result, err = spool.Replay(t.Context(), client, sp)
+	require.NoError(t, err)
+	assert.Equal(t, spool.Result{Applied: 3, Refused: 1}, result)
+
+	entries, err = sp.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	software := flaky.Software("")
+	require.Len(t, software, 2)
+	assert.Equal(t, existing.ID, software[0].ID)
+	assert.Equal(t, "new", software[0].PubliccodeYml)
+	assert.Equal(t, "https://example.org/app", software[1].URL)
+	assert.Equal(t, "v2", software[1].PubliccodeYml)
+	assert.False(t, software[1].Active)
+
+	logs := flaky.Logs()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "log", logs[0].Message)
+}
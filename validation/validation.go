@@ -0,0 +1,150 @@
+// Package validation represents the results of the checks on publiccode.yml
+// files as structured entries, so that the consumers of the crawler logs
+// don't have to parse the human readable messages.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+)
+
+// Severity is how serious an Entry is: errors make the file invalid,
+// warnings don't.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule IDs, identifying the check that produced an Entry.
+const (
+	// RuleParser is the validation of publiccode-parser-go.
+	RuleParser = "publiccode-parser"
+	// RuleSyntax is a file that can't be parsed at all.
+	RuleSyntax = "publiccode-syntax"
+	// RuleURLRepository checks that the url declared in the file is the
+	// repository the file is in.
+	RuleURLRepository = "url-repository"
+	// RuleURLHost checks that a file known only by its URL is on the host
+	// of the url it declares, when its repository can't be told from its URL.
+	RuleURLHost = "url-host"
+	// RuleOrganisationURI checks that organisation.uri is the publisher's
+	// alternativeId in the publishers namespace.
+	RuleOrganisationURI = "organisation-uri"
+)
+
+// Entry is an error or warning on a publiccode.yml file.
+type Entry struct {
+	// Key is the path of the key the entry is about, eg. "legal.license",
+	// empty for the whole file.
+	Key string `json:"key"`
+	// Line and Column are the position in the file, 0 if unknown.
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	RuleID   string   `json:"ruleId"`
+}
+
+// Error formats e as publiccode-parser-go does.
+func (e Entry) Error() string {
+	key := ""
+	if e.Key != "" {
+		key = e.Key + ": "
+	}
+
+	return fmt.Sprintf("publiccode.yml:%d:%d: %s: %s%s", e.Line, e.Column, e.Severity, key, e.Message)
+}
+
+// FromError returns the entries of err, as returned by the publiccode.yml
+// parser or by a check returning an Entry. Errors of other types become a
+// single RuleSyntax error. A nil err has no entries.
+func FromError(err error) []Entry {
+	if err == nil {
+		return nil
+	}
+
+	var results publiccode.ValidationResults
+	if errors.As(err, &results) {
+		entries := make([]Entry, 0, len(results))
+		for _, res := range results {
+			entries = append(entries, FromError(res)...)
+		}
+
+		return entries
+	}
+
+	var (
+		entry      Entry
+		validation publiccode.ValidationError
+		warning    publiccode.ValidationWarning
+	)
+
+	switch {
+	case errors.As(err, &entry):
+		return []Entry{entry}
+	case errors.As(err, &validation):
+		return []Entry{{
+			Key: validation.Key, Line: validation.Line, Column: validation.Column,
+			Severity: SeverityError, Message: validation.Description, RuleID: RuleParser,
+		}}
+	case errors.As(err, &warning):
+		return []Entry{{
+			Key: warning.Key, Line: warning.Line, Column: warning.Column,
+			Severity: SeverityWarning, Message: warning.Description, RuleID: RuleParser,
+		}}
+	default:
+		return []Entry{{Severity: SeverityError, Message: err.Error(), RuleID: RuleSyntax}}
+	}
+}
+
+// HasErrors reports whether any of entries is an error.
+func HasErrors(entries []Entry) bool {
+	for _, entry := range entries {
+		if entry.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// messagePrefix starts the line with the entries in a log message.
+const messagePrefix = "validation: "
+
+// FormatMessage returns a log message with text followed, if there are
+// entries, by a line with them as a JSON array.
+func FormatMessage(text string, entries []Entry) string {
+	if len(entries) == 0 {
+		return text
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		// Entries only have strings and ints.
+		panic(err)
+	}
+
+	return strings.TrimRight(text, "\n") + "\n" + messagePrefix + string(data)
+}
+
+// ParseMessage splits a message made with FormatMessage back into the text
+// and the entries.
+func ParseMessage(message string) (string, []Entry, error) {
+	idx := strings.LastIndex(message, "\n"+messagePrefix)
+	if idx == -1 {
+		return message, nil, nil
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal([]byte(message[idx+1+len(messagePrefix):]), &entries); err != nil {
+		return message, nil, fmt.Errorf("invalid validation entries: %w", err)
+	}
+
+	return message[:idx], entries, nil
+}
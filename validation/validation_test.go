@@ -0,0 +1,70 @@
+package validation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/italia/publiccode-crawler/v4/validation"
+	publiccode "github.com/italia/publiccode-parser-go/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromError(t *testing.T) {
+	assert.Empty(t, validation.FromError(nil))
+
+	results := publiccode.ValidationResults{
+		publiccode.ValidationError{Key: "legal.license", Description: "required", Line: 3, Column: 1},
+		publiccode.ValidationWarning{Key: "description.it", Description: "too short", Line: 10, Column: 5},
+	}
+
+	entries := validation.FromError(results)
+	assert.Equal(t, []validation.Entry{
+		{
+			Key: "legal.license", Line: 3, Column: 1,
+			Severity: validation.SeverityError, Message: "required", RuleID: validation.RuleParser,
+		},
+		{
+			Key: "description.it", Line: 10, Column: 5,
+			Severity: validation.SeverityWarning, Message: "too short", RuleID: validation.RuleParser,
+		},
+	}, entries)
+	assert.True(t, validation.HasErrors(entries))
+	assert.False(t, validation.HasErrors(entries[1:]))
+
+	assert.Equal(t, []validation.Entry{
+		{Severity: validation.SeverityError, Message: "boom", RuleID: validation.RuleSyntax},
+	}, validation.FromError(errors.New("boom")))
+
+	entry := validation.Entry{Key: "url", Severity: validation.SeverityError, Message: "m", RuleID: "rule"}
+	assert.Equal(t, []validation.Entry{entry}, validation.FromError(entry))
+	assert.Equal(t, "publiccode.yml:0:0: error: url: m", entry.Error())
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "text", validation.FormatMessage("text", nil))
+
+	entries := []validation.Entry{
+		{Key: "name", Line: 2, Column: 1, Severity: validation.SeverityWarning, Message: "m", RuleID: "r"},
+	}
+
+	message := validation.FormatMessage("[acme/app] BAD publiccode.yml\n", entries)
+	assert.Equal(t,
+		"[acme/app] BAD publiccode.yml\n"+
+			`validation: [{"key":"name","line":2,"column":1,"severity":"warning","message":"m","ruleId":"r"}]`,
+		message,
+	)
+
+	text, parsed, err := validation.ParseMessage(message)
+	require.NoError(t, err)
+	assert.Equal(t, "[acme/app] BAD publiccode.yml", text)
+	assert.Equal(t, entries, parsed)
+
+	text, parsed, err = validation.ParseMessage("plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", text)
+	assert.Nil(t, parsed)
+
+	_, _, err = validation.ParseMessage("text\nvalidation: {")
+	assert.Error(t, err)
+}